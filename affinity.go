@@ -0,0 +1,42 @@
+package sqlite_base
+
+import "strings"
+
+// TypeComparator decides whether a column's declared type in the database
+// (actual) satisfies the expected type in Schema.Columns.
+type TypeComparator func(expected, actual string) bool
+
+// ExactMatch requires the declared types to be identical.
+func ExactMatch(expected, actual string) bool {
+	return expected == actual
+}
+
+// CaseInsensitiveMatch compares declared types ignoring case. It is the
+// default comparator.
+func CaseInsensitiveMatch(expected, actual string) bool {
+	return strings.EqualFold(expected, actual)
+}
+
+// AffinityMatch accepts any declared type with the same SQLite type
+// affinity, so VARCHAR(255) matches TEXT and BIGINT matches INTEGER.
+func AffinityMatch(expected, actual string) bool {
+	return typeAffinity(expected) == typeAffinity(actual)
+}
+
+// typeAffinity applies SQLite's column affinity rules, in their documented
+// order, to a declared type.
+func typeAffinity(declared string) string {
+	upper := strings.ToUpper(declared)
+	switch {
+	case strings.Contains(upper, "INT"):
+		return "INTEGER"
+	case strings.Contains(upper, "CHAR"), strings.Contains(upper, "CLOB"), strings.Contains(upper, "TEXT"):
+		return "TEXT"
+	case strings.Contains(upper, "BLOB"), strings.TrimSpace(upper) == "":
+		return "BLOB"
+	case strings.Contains(upper, "REAL"), strings.Contains(upper, "FLOA"), strings.Contains(upper, "DOUB"):
+		return "REAL"
+	}
+
+	return "NUMERIC"
+}
@@ -0,0 +1,51 @@
+package sqlite_base
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTypeAffinity(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"INTEGER":       "INTEGER",
+		"BIGINT":        "INTEGER",
+		"VARCHAR(255)":  "TEXT",
+		"CLOB":          "TEXT",
+		"BLOB":          "BLOB",
+		"":              "BLOB",
+		"DOUBLE":        "REAL",
+		"FLOATING":      "REAL",
+		"DECIMAL(10,2)": "NUMERIC",
+		"DATETIME":      "NUMERIC",
+		"POINT":         "INTEGER",
+	}
+	for declared, want := range tests {
+		if got := typeAffinity(declared); got != want {
+			t.Fatalf("typeAffinity(%q) = %s, want %s", declared, got, want)
+		}
+	}
+}
+
+func TestValidateSchema_TypeComparator(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE users (id bigint PRIMARY KEY, email VARCHAR(255))")
+
+	columns := map[string]map[string]string{"users": {"id": "INTEGER", "email": "TEXT"}}
+	if err := ValidateSchema(db, Schema{Columns: columns}); !errors.Is(err, ErrColumnTypeMismatch) {
+		t.Fatalf("expected default comparator to reject affinity matches, got %v", err)
+	}
+	if err := ValidateSchema(db, Schema{Columns: columns, TypeComparator: AffinityMatch}); err != nil {
+		t.Fatalf("expected affinity comparator to accept, got %v", err)
+	}
+
+	exact := map[string]map[string]string{"users": {"id": "BIGINT"}}
+	if err := ValidateSchema(db, Schema{Columns: exact}); err != nil {
+		t.Fatalf("expected case-insensitive default to accept, got %v", err)
+	}
+	if err := ValidateSchema(db, Schema{Columns: exact, TypeComparator: ExactMatch}); !errors.Is(err, ErrColumnTypeMismatch) {
+		t.Fatalf("expected exact comparator to reject case difference, got %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package sqlite_base
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var createTableHeader = regexp.MustCompile(`(?is)^\s*CREATE\s+TABLE\s+(IF\s+NOT\s+EXISTS\s+)?("(?:[^"]|"")*"|\x60[^\x60]*\x60|\[[^\]]*\]|[^\s(]+)\s*\(`)
+
+var autoincrementPattern = regexp.MustCompile(`(?i)\bAUTOINCREMENT\b`)
+
+// ConvertToWithoutRowid rebuilds table as a WITHOUT ROWID table inside a
+// transaction: the data is copied into a new table, the old one is dropped and
+// the new one renamed, and the table's indexes and triggers are recreated.
+// Foreign keys referencing the table and views using it are preserved, as
+// with RecreateTable.
+// The table must declare a primary key that does not rely on rowid
+// assignment, i.e. not an INTEGER PRIMARY KEY alias or AUTOINCREMENT column.
+func ConvertToWithoutRowid(db *sqlx.DB, table string) error {
+	createSQL, err := tableSQL(db, table)
+	if err != nil {
+		return err
+	}
+	if hasTableOption(createSQL, "WITHOUT ROWID") {
+		return fmt.Errorf("table %s is already WITHOUT ROWID", table)
+	}
+	if autoincrementPattern.MatchString(createSQL) {
+		return fmt.Errorf("table %s uses AUTOINCREMENT, which WITHOUT ROWID tables do not support", table)
+	}
+
+	var pkCount int
+	if err := db.Get(&pkCount, `SELECT COUNT(1) FROM pragma_table_info(?) WHERE pk > 0`, table); err != nil {
+		return fmt.Errorf("query table info for %s: %w", table, err)
+	}
+	if pkCount == 0 {
+		return fmt.Errorf("table %s has no explicit primary key", table)
+	}
+	alias, err := ValidateRowidAlias(db, table)
+	if err != nil {
+		return err
+	}
+	if alias {
+		return fmt.Errorf("table %s has an INTEGER PRIMARY KEY rowid alias; its keys are assigned by the rowid", table)
+	}
+
+	tmp := table + "_without_rowid"
+	newSQL, err := withoutRowidSQL(createSQL, tmp)
+	if err != nil {
+		return err
+	}
+
+	return rebuildTable(db, table, tmp, newSQL)
+}
+
+// ColumnSpec describes a column for AddColumn and TableDefinition. Default is
+// a SQL expression, e.g. "0" or "'pending'", and AddColumn requires it for
+// NOT NULL columns because existing rows need a value. PrimaryKey and Unique
+// are only valid in a TableDefinition: ALTER TABLE cannot add such columns.
+type ColumnSpec struct {
+	Name       string
+	Type       string
+	NotNull    bool
+	PrimaryKey bool
+	Default    string
+	Unique     bool
+}
+
+func (c ColumnSpec) definition() string {
+	def := quoteIdent(c.Name)
+	if c.Type != "" {
+		def += " " + c.Type
+	}
+	if c.PrimaryKey {
+		def += " PRIMARY KEY"
+	}
+	if c.NotNull {
+		def += " NOT NULL"
+	}
+	if c.Unique {
+		def += " UNIQUE"
+	}
+	if c.Default != "" {
+		def += " DEFAULT " + c.Default
+	}
+
+	return def
+}
+
+// AddColumn runs ALTER TABLE ... ADD COLUMN. SQLite cannot add PRIMARY KEY or
+// UNIQUE columns this way; use RecreateTable for those.
+func AddColumn(db *sqlx.DB, table string, spec ColumnSpec) error {
+	stmt, err := addColumnSQL(table, spec)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(stmt); err != nil {
+		return fmt.Errorf("add column %s to %s: %w", spec.Name, table, err)
+	}
+
+	return nil
+}
+
+func addColumnSQL(table string, spec ColumnSpec) (string, error) {
+	if strings.TrimSpace(spec.Name) == "" {
+		return "", errors.New("column name is required")
+	}
+	if spec.NotNull && spec.Default == "" {
+		return "", fmt.Errorf("table %s: column %s is NOT NULL and needs a default", table, spec.Name)
+	}
+	if spec.PrimaryKey || spec.Unique {
+		return "", fmt.Errorf("table %s: column %s cannot be added as PRIMARY KEY or UNIQUE; use RecreateTable", table, spec.Name)
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdent(table), spec.definition()), nil
+}
+
+// RenameColumn runs ALTER TABLE ... RENAME COLUMN, which needs SQLite 3.25.0
+// or later. On older libraries it returns an error; rebuild the table with
+// RecreateTable instead.
+func RenameColumn(db *sqlx.DB, table, oldName, newName string) error {
+	version, err := sqliteVersion(db)
+	if err != nil {
+		return err
+	}
+	if !versionAtLeast(version, 3, 25, 0) {
+		return fmt.Errorf("rename column requires SQLite 3.25.0, running %d.%d.%d; use RecreateTable", version[0], version[1], version[2])
+	}
+
+	stmt := fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", quoteIdent(table), quoteIdent(oldName), quoteIdent(newName))
+	if _, err := db.Exec(stmt); err != nil {
+		return fmt.Errorf("rename column %s.%s: %w", table, oldName, err)
+	}
+
+	return nil
+}
+
+// RenameTable runs ALTER TABLE ... RENAME TO. Indexes, triggers and foreign
+// keys follow the table; on SQLite 3.26.0 and later, unless
+// legacy_alter_table is on, references to it in triggers and views are
+// updated as well.
+func RenameTable(db *sqlx.DB, oldName, newName string) error {
+	stmt := fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdent(oldName), quoteIdent(newName))
+	if _, err := db.Exec(stmt); err != nil {
+		return fmt.Errorf("rename table %s: %w", oldName, err)
+	}
+
+	return nil
+}
+
+// RecreateTable replaces table with the definition in createSQL for changes
+// ALTER TABLE cannot express, such as dropping or retyping a column: a new
+// table is created, columns present in both definitions are copied by name,
+// the old table is dropped and the new one renamed, and the table's indexes
+// and triggers are recreated, all in one transaction. Foreign key
+// enforcement is suspended meanwhile, so ON DELETE actions of child tables
+// do not fire, and the change is rejected if it leaves a foreign key
+// violation; views using the table keep working. The table name in
+// createSQL is ignored.
+func RecreateTable(db *sqlx.DB, table, createSQL string) error {
+	exists, err := tableExists(db, table)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &ValidationError{Err: ErrTableMissing, Table: table}
+	}
+
+	tmp := table + "_recreate"
+	newSQL, err := renameCreateTable(createSQL, tmp)
+	if err != nil {
+		return err
+	}
+
+	return rebuildTable(db, table, tmp, newSQL)
+}
+
+// rebuildTable copies table into a new table created by createSQL under the
+// name tmp, swaps the two and restores the table's indexes and triggers.
+// Columns are copied by name, so createSQL may drop or retype columns.
+//
+// It follows SQLite's documented procedure for schema changes on one pinned
+// connection: foreign keys are switched off before the transaction, so that
+// dropping the old table neither cascades to nor fails on child rows, and
+// legacy_alter_table is switched on, so that the rename leaves views and
+// triggers that refer to the table by name untouched. When foreign keys were
+// on, PRAGMA foreign_key_check must pass before the transaction commits.
+func rebuildTable(db *sqlx.DB, table, tmp, createSQL string) (err error) {
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("checkout connection: %w", err)
+	}
+	defer conn.Close()
+
+	var foreignKeys, legacyAlter bool
+	if err := conn.GetContext(ctx, &foreignKeys, "PRAGMA foreign_keys"); err != nil {
+		return fmt.Errorf("read foreign keys setting: %w", err)
+	}
+	if err := conn.GetContext(ctx, &legacyAlter, "PRAGMA legacy_alter_table"); err != nil {
+		return fmt.Errorf("read legacy alter table setting: %w", err)
+	}
+	restore := []string{fmt.Sprintf("PRAGMA legacy_alter_table = %t", legacyAlter)}
+	if foreignKeys {
+		restore = append(restore, "PRAGMA foreign_keys = ON")
+	}
+	defer func() {
+		for _, stmt := range restore {
+			if _, restoreErr := conn.ExecContext(ctx, stmt); restoreErr != nil {
+				// Do not hand a connection with the wrong settings back to
+				// the pool.
+				_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+				err = errors.Join(err, fmt.Errorf("restore connection settings: %w", restoreErr))
+				return
+			}
+		}
+	}()
+	for _, stmt := range []string{"PRAGMA foreign_keys = OFF", "PRAGMA legacy_alter_table = ON"} {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("prepare rebuild of %s: %w", table, err)
+		}
+	}
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	if err := copyTable(tx, table, tmp, createSQL, foreignKeys); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rbErr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+type foreignKeyViolation struct {
+	Table  string        `db:"table"`
+	RowID  sql.NullInt64 `db:"rowid"`
+	Parent string        `db:"parent"`
+	FKID   int           `db:"fkid"`
+}
+
+func copyTable(tx *sqlx.Tx, table, tmp, createSQL string, checkForeignKeys bool) error {
+	var dependents []string
+	err := tx.Select(&dependents, `SELECT sql FROM sqlite_master WHERE type IN ('index', 'trigger') AND tbl_name = ? COLLATE NOCASE AND sql IS NOT NULL ORDER BY type, name`, table)
+	if err != nil {
+		return fmt.Errorf("read dependents of %s: %w", table, err)
+	}
+
+	if _, err := tx.Exec(createSQL); err != nil {
+		return fmt.Errorf("create table %s: %w", tmp, err)
+	}
+
+	var columns []string
+	err = tx.Select(&columns, `SELECT name FROM pragma_table_info(?) WHERE name COLLATE NOCASE IN (SELECT name FROM pragma_table_info(?))`, tmp, table)
+	if err != nil {
+		return fmt.Errorf("match columns of %s: %w", table, err)
+	}
+	for i, column := range columns {
+		columns[i] = quoteIdent(column)
+	}
+	list := strings.Join(columns, ", ")
+
+	stmts := []string{
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", quoteIdent(tmp), list, list, quoteIdent(table)),
+		"DROP TABLE " + quoteIdent(table),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdent(tmp), quoteIdent(table)),
+	}
+	stmts = append(stmts, dependents...)
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("rebuild table %s: %w", table, err)
+		}
+	}
+
+	if !checkForeignKeys {
+		return nil
+	}
+	var violations []foreignKeyViolation
+	if err := tx.Select(&violations, "PRAGMA foreign_key_check"); err != nil {
+		return fmt.Errorf("check foreign keys: %w", err)
+	}
+	if len(violations) > 0 {
+		v := violations[0]
+		return fmt.Errorf("rebuild table %s: %d foreign key violations, first in %s referencing %s", table, len(violations), v.Table, v.Parent)
+	}
+
+	return nil
+}
+
+// renameCreateTable rewrites a stored CREATE TABLE statement to create a
+// table called name instead.
+func renameCreateTable(createSQL, name string) (string, error) {
+	loc := createTableHeader.FindStringIndex(createSQL)
+	if loc == nil {
+		return "", fmt.Errorf("cannot parse table definition: %s", createSQL)
+	}
+
+	return "CREATE TABLE " + quoteIdent(name) + " (" + createSQL[loc[1]:], nil
+}
+
+func withoutRowidSQL(createSQL, name string) (string, error) {
+	renamed, err := renameCreateTable(createSQL, name)
+	if err != nil {
+		return "", err
+	}
+
+	end := strings.LastIndex(renamed, ")")
+	options := strings.TrimSpace(renamed[end+1:])
+	if options == "" {
+		return renamed[:end+1] + " WITHOUT ROWID", nil
+	}
+
+	return renamed[:end+1] + " WITHOUT ROWID, " + options, nil
+}
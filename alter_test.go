@@ -0,0 +1,174 @@
+package sqlite_base
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertToWithoutRowid_PreservesData(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE countries (code TEXT PRIMARY KEY, name TEXT NOT NULL)",
+		"CREATE INDEX idx_countries_name ON countries (name)",
+		"INSERT INTO countries (code, name) VALUES ('TH', 'Thailand'), ('JP', 'Japan')",
+	)
+
+	if err := ConvertToWithoutRowid(db, "countries"); err != nil {
+		t.Fatalf("convert failed: %v", err)
+	}
+
+	createSQL, err := tableSQL(db, "countries")
+	if err != nil {
+		t.Fatalf("read table sql failed: %v", err)
+	}
+	if !hasTableOption(createSQL, "WITHOUT ROWID") {
+		t.Fatalf("expected WITHOUT ROWID table, got: %s", createSQL)
+	}
+
+	var name string
+	if err := db.Get(&name, "SELECT name FROM countries WHERE code = 'TH'"); err != nil {
+		t.Fatalf("select failed: %v", err)
+	}
+	if name != "Thailand" {
+		t.Fatalf("expected Thailand, got %s", name)
+	}
+
+	exists, err := IndexExists(db, "idx_countries_name")
+	if err != nil || !exists {
+		t.Fatalf("expected index to be recreated, got %t, %v", exists, err)
+	}
+}
+
+func TestConvertToWithoutRowid_RejectsUnsuitableTables(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE no_pk (name TEXT)",
+		"CREATE TABLE alias (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE already (code TEXT PRIMARY KEY) WITHOUT ROWID",
+	)
+
+	for _, table := range []string{"no_pk", "alias", "already", "missing"} {
+		if err := ConvertToWithoutRowid(db, table); err == nil {
+			t.Fatalf("%s: expected conversion to be rejected", table)
+		}
+	}
+}
+
+func TestAlterHelpers(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, legacy TEXT)",
+		"CREATE INDEX idx_users_name ON users (name)",
+		"INSERT INTO users (name, legacy) VALUES ('ada', 'x'), ('grace', 'y')",
+	)
+
+	if err := AddColumn(db, "users", ColumnSpec{Name: "status", Type: "TEXT", NotNull: true, Default: "'active'"}); err != nil {
+		t.Fatalf("add column failed: %v", err)
+	}
+	if err := AddColumn(db, "users", ColumnSpec{Name: "score", Type: "INTEGER", NotNull: true}); err == nil {
+		t.Fatal("expected NOT NULL column without default to fail")
+	}
+	if err := RenameColumn(db, "users", "name", "full_name"); err != nil {
+		t.Fatalf("rename column failed: %v", err)
+	}
+	if err := RenameTable(db, "users", "people"); err != nil {
+		t.Fatalf("rename table failed: %v", err)
+	}
+
+	err := RecreateTable(db, "people", `CREATE TABLE people (id INTEGER PRIMARY KEY, full_name TEXT NOT NULL, status TEXT NOT NULL DEFAULT 'active')`)
+	if err != nil {
+		t.Fatalf("recreate table failed: %v", err)
+	}
+
+	err = ValidateExactColumns(db, map[string][]string{"people": {"id", "full_name", "status"}})
+	if err != nil {
+		t.Fatalf("unexpected columns after recreate: %v", err)
+	}
+	var names []string
+	if err := db.Select(&names, "SELECT full_name FROM people WHERE status = 'active' ORDER BY id"); err != nil {
+		t.Fatalf("select failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "ada" {
+		t.Fatalf("unexpected rows after recreate: %v", names)
+	}
+	exists, err := IndexExists(db, "idx_users_name")
+	if err != nil || !exists {
+		t.Fatalf("expected index to survive recreate, exists=%v err=%v", exists, err)
+	}
+}
+
+func TestRecreateTable_KeepsChildRowsAndViews(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"PRAGMA foreign_keys = ON",
+		"CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT NOT NULL)",
+		"CREATE TABLE books (id INTEGER PRIMARY KEY, author_id INTEGER NOT NULL REFERENCES authors (id) ON DELETE CASCADE)",
+		"CREATE VIEW author_names AS SELECT name FROM authors",
+		"INSERT INTO authors (id, name) VALUES (1, 'ada'), (2, 'grace')",
+		"INSERT INTO books (author_id) VALUES (1), (1), (2)",
+	)
+
+	err := RecreateTable(db, "authors", `CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT NOT NULL, bio TEXT)`)
+	if err != nil {
+		t.Fatalf("recreate table failed: %v", err)
+	}
+
+	var books int
+	if err := db.Get(&books, "SELECT COUNT(*) FROM books"); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if books != 3 {
+		t.Fatalf("expected child rows to survive, got %d", books)
+	}
+	var names []string
+	if err := db.Select(&names, "SELECT name FROM author_names ORDER BY name"); err != nil {
+		t.Fatalf("select from view failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "ada" {
+		t.Fatalf("unexpected view rows: %v", names)
+	}
+	var foreignKeys bool
+	if err := db.Get(&foreignKeys, "PRAGMA foreign_keys"); err != nil {
+		t.Fatalf("read foreign keys failed: %v", err)
+	}
+	if !foreignKeys {
+		t.Fatal("expected foreign keys to be enabled again")
+	}
+
+	err = RecreateTable(db, "authors", `CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT NOT NULL CHECK (name <> 'grace'))`)
+	if err == nil {
+		t.Fatal("expected a failing copy to be reported")
+	}
+	if err := db.Get(&books, "SELECT COUNT(*) FROM books"); err != nil || books != 3 {
+		t.Fatalf("expected child rows to survive a failed rebuild, got %d, %v", books, err)
+	}
+}
+
+func TestRecreateTable_RejectsForeignKeyViolations(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"PRAGMA foreign_keys = ON",
+		"CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT NOT NULL)",
+		"CREATE TABLE publishers (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE books (id INTEGER PRIMARY KEY, author_id INTEGER REFERENCES authors (id), author_name TEXT)",
+		"INSERT INTO authors (id, name) VALUES (1, 'ada')",
+		"INSERT INTO books (author_id, author_name) VALUES (1, 'ada')",
+	)
+
+	err := RecreateTable(db, "books", `CREATE TABLE books (id INTEGER PRIMARY KEY, author_id INTEGER REFERENCES publishers (id), author_name TEXT)`)
+	if err == nil || !strings.Contains(err.Error(), "foreign key violations") {
+		t.Fatalf("expected a foreign key error, got %v", err)
+	}
+	createSQL, err := tableSQL(db, "books")
+	if err != nil {
+		t.Fatalf("read table sql failed: %v", err)
+	}
+	if !strings.Contains(createSQL, "REFERENCES authors") {
+		t.Fatalf("expected the failed rebuild to roll back, got %s", createSQL)
+	}
+}
@@ -0,0 +1,110 @@
+package sqlite_base
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Attach runs ATTACH DATABASE on whichever pooled connection executes it.
+// Attachments are scoped to a single SQLite connection, so, like Changes,
+// Attach is only meaningful when the pool is limited to one connection. Use
+// Config.Attachments to attach a database to every pooled connection, or
+// ConnAttach on a checked-out *sqlx.Conn. SQLite allows at most 10 attached
+// databases per connection by default; exceeding the limit returns SQLite's
+// "too many attached databases" error.
+func Attach(db *sqlx.DB, path, alias string) error {
+	return attach(context.Background(), db, path, alias)
+}
+
+// Detach runs DETACH DATABASE on whichever pooled connection executes it,
+// which, as with Attach, is only the connection that attached alias when the
+// pool is limited to one connection.
+func Detach(db *sqlx.DB, alias string) error {
+	return detach(context.Background(), db, alias)
+}
+
+// ConnAttach attaches the database at path as alias on conn. Only that
+// connection sees the attachment; other pooled connections are unaffected.
+func ConnAttach(ctx context.Context, conn *sqlx.Conn, path, alias string) error {
+	return attach(ctx, conn, path, alias)
+}
+
+// ConnDetach detaches alias from conn, leaving attachments on other pooled
+// connections in place.
+func ConnDetach(ctx context.Context, conn *sqlx.Conn, alias string) error {
+	return detach(ctx, conn, alias)
+}
+
+func attach(ctx context.Context, e sqlx.ExecerContext, path, alias string) error {
+	if err := validateAlias(alias); err != nil {
+		return err
+	}
+	if strings.TrimSpace(path) == "" {
+		return errors.New("attach path is required")
+	}
+	if _, err := e.ExecContext(ctx, "ATTACH DATABASE ? AS ?", path, alias); err != nil {
+		return fmt.Errorf("attach database %s as %s: %w", path, alias, err)
+	}
+
+	return nil
+}
+
+func detach(ctx context.Context, e sqlx.ExecerContext, alias string) error {
+	if err := validateAlias(alias); err != nil {
+		return err
+	}
+	if _, err := e.ExecContext(ctx, "DETACH DATABASE ?", alias); err != nil {
+		return fmt.Errorf("detach database %s: %w", alias, err)
+	}
+
+	return nil
+}
+
+func validateAlias(alias string) error {
+	if strings.TrimSpace(alias) == "" {
+		return errors.New("attach alias is required")
+	}
+	if strings.EqualFold(alias, "main") || strings.EqualFold(alias, "temp") {
+		return fmt.Errorf("attach alias %s is reserved", alias)
+	}
+
+	return nil
+}
+
+type attachment struct {
+	alias string
+	path  string
+}
+
+// connectionAttachments validates Config.Attachments and orders them by alias
+// so every pooled connection sees the same schema names.
+func connectionAttachments(attachments map[string]string) ([]attachment, error) {
+	list := make([]attachment, 0, len(attachments))
+	for alias, path := range attachments {
+		if err := validateAlias(alias); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(path) == "" {
+			return nil, fmt.Errorf("attach %s: path is required", alias)
+		}
+		list = append(list, attachment{alias: alias, path: path})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].alias < list[j].alias })
+
+	return list, nil
+}
+
+func attachConn(conn *sqlite3.SQLiteConn, a attachment) error {
+	if _, err := conn.Exec("ATTACH DATABASE ? AS ?", []driver.Value{a.path, a.alias}); err != nil {
+		return fmt.Errorf("attach database %s as %s: %w", a.path, a.alias, err)
+	}
+
+	return nil
+}
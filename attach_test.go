@@ -0,0 +1,81 @@
+package sqlite_base
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestOpen_AttachmentsOnEveryConnection(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cold, err := Open(Config{Path: filepath.Join(dir, "cold.sqlite")})
+	if err != nil {
+		t.Fatalf("open cold failed: %v", err)
+	}
+	cold.MustExec("CREATE TABLE archive (id INTEGER PRIMARY KEY)")
+	cold.MustExec("INSERT INTO archive (id) VALUES (1), (2)")
+	_ = cold.Close()
+
+	db, err := Open(Config{
+		Path:        filepath.Join(dir, "hot.sqlite"),
+		Attachments: map[string]string{"cold": filepath.Join(dir, "cold.sqlite")},
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	var conns []*sqlx.Conn
+	for i := 0; i < 2; i++ {
+		conn, err := db.Connx(ctx)
+		if err != nil {
+			t.Fatalf("connx failed: %v", err)
+		}
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		var n int
+		if err := conn.GetContext(ctx, &n, "SELECT COUNT(*) FROM cold.archive"); err != nil {
+			t.Fatalf("query attached table failed: %v", err)
+		}
+		if n != 2 {
+			t.Fatalf("expected 2 rows, got %d", n)
+		}
+		_ = conn.Close()
+	}
+}
+
+func TestConnAttach_Detach(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t)
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		t.Fatalf("connx failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	path := filepath.Join(t.TempDir(), "other.sqlite")
+	if err := ConnAttach(ctx, conn, path, "other"); err != nil {
+		t.Fatalf("attach failed: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, "CREATE TABLE other.notes (id INTEGER)"); err != nil {
+		t.Fatalf("create in attached db failed: %v", err)
+	}
+	if err := ConnAttach(ctx, conn, path, "main"); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Fatalf("expected reserved alias error, got %v", err)
+	}
+	if err := ConnDetach(ctx, conn, "other"); err != nil {
+		t.Fatalf("detach failed: %v", err)
+	}
+	if err := ConnDetach(ctx, conn, "other"); err == nil {
+		t.Fatal("expected detaching an unknown alias to fail")
+	}
+}
@@ -0,0 +1,90 @@
+package sqlite_base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Backup copies the main database of src to a new file at dstPath with
+// SQLite's online backup API. Unlike copying the file, this is safe while
+// src is being written to, including in WAL mode: the copy is a consistent
+// snapshot that includes committed WAL content. dstPath must not exist, and
+// is removed again when the backup fails so that it can be retried.
+func Backup(src *sqlx.DB, dstPath string) (err error) {
+	if err := checkDatabasePath(dstPath); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dstPath); err == nil {
+		return fmt.Errorf("backup destination %s already exists", dstPath)
+	}
+
+	ctx := context.Background()
+	conn, err := src.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("checkout connection: %w", err)
+	}
+	defer conn.Close()
+
+	dc, err := (&sqlite3.SQLiteDriver{}).Open(dstPath)
+	if err != nil {
+		return fmt.Errorf("open backup destination %s: %w", dstPath, err)
+	}
+	dst := dc.(*sqlite3.SQLiteConn)
+	defer func() {
+		_ = dst.Close()
+		if err != nil {
+			_ = os.Remove(dstPath)
+			_ = os.Remove(dstPath + "-journal")
+		}
+	}()
+
+	return conn.Raw(func(driverConn any) error {
+		srcConn, ok := sqliteConn(driverConn)
+		if !ok {
+			return errors.New("backup requires a go-sqlite3 connection")
+		}
+
+		backup, err := dst.Backup("main", srcConn, "main")
+		if err != nil {
+			return fmt.Errorf("start backup: %w", err)
+		}
+		if _, err := backup.Step(-1); err != nil {
+			_ = backup.Finish()
+			return fmt.Errorf("copy database: %w", err)
+		}
+		if err := backup.Finish(); err != nil {
+			return fmt.Errorf("finish backup: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Clone backs src up to dstPath like Backup and opens the copy, which is
+// independent of src, e.g. a throwaway copy of a production-shaped database
+// for tests.
+func Clone(src *sqlx.DB, dstPath string) (*sqlx.DB, error) {
+	if err := Backup(src, dstPath); err != nil {
+		return nil, err
+	}
+
+	return Open(Config{Path: dstPath})
+}
+
+// sqliteConn returns the go-sqlite3 connection behind a driver connection
+// from Open, OpenDB or a plain sql.Open("sqlite3", ...).
+func sqliteConn(driverConn any) (*sqlite3.SQLiteConn, bool) {
+	switch conn := driverConn.(type) {
+	case *sqlite3.SQLiteConn:
+		return conn, true
+	case *instrumentedConn:
+		return conn.SQLiteConn, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,102 @@
+package sqlite_base
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestClone_CopiesLiveDatabase(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src, err := Open(Config{
+		Path:                filepath.Join(dir, "src.sqlite"),
+		DSN:                 DSN{JournalMode: "WAL"},
+		DefaultQueryTimeout: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = src.Close() })
+	src.MustExec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	src.MustExec("INSERT INTO users (name) VALUES ('alice'), ('bob')")
+
+	clone, err := Clone(src, filepath.Join(dir, "clone.sqlite"))
+	if err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	t.Cleanup(func() { _ = clone.Close() })
+
+	var count int
+	if err := clone.Get(&count, "SELECT COUNT(*) FROM users"); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 rows in clone, got %d", count)
+	}
+
+	clone.MustExec("DELETE FROM users")
+	if err := src.Get(&count, "SELECT COUNT(*) FROM users"); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected clone to be independent of source, got %d rows", count)
+	}
+
+	err = Backup(src, filepath.Join(dir, "clone.sqlite"))
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected existing destination to be rejected, got %v", err)
+	}
+}
+
+func TestBackup_RemovesDestinationOnFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src.sqlite")
+	src, err := Open(Config{DSN: DSN{Path: srcPath, BusyTimeout: time.Millisecond}})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = src.Close() })
+	src.MustExec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+
+	// An exclusive lock held by another connection keeps the backup from
+	// reading the source.
+	locker, err := sqlx.Open("sqlite3", srcPath)
+	if err != nil {
+		t.Fatalf("open locker failed: %v", err)
+	}
+	t.Cleanup(func() { _ = locker.Close() })
+	lock, err := locker.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("get connection failed: %v", err)
+	}
+	if _, err := lock.ExecContext(context.Background(), "BEGIN EXCLUSIVE"); err != nil {
+		t.Fatalf("begin exclusive failed: %v", err)
+	}
+
+	dstPath := filepath.Join(dir, "backup.sqlite")
+	if err := Backup(src, dstPath); err == nil {
+		t.Fatal("expected backup of a locked database to fail")
+	}
+	if _, err := os.Stat(dstPath); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected failed backup to remove %s, got %v", dstPath, err)
+	}
+
+	if _, err := lock.ExecContext(context.Background(), "ROLLBACK"); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	_ = lock.Close()
+	if err := Backup(src, dstPath); err != nil {
+		t.Fatalf("retried backup failed: %v", err)
+	}
+}
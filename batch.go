@@ -0,0 +1,290 @@
+package sqlite_base
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// maxBoundParams is SQLite's historical SQLITE_MAX_VARIABLE_NUMBER default,
+// the lowest limit any build is likely to enforce.
+const maxBoundParams = 999
+
+// BatchInsert inserts rows into table with multi-row INSERT statements of at
+// most chunkSize rows each, all inside one transaction, and returns the number
+// of rows inserted. Chunks are shrunk further when needed to stay under the
+// bound parameter limit. Every row must have the same set of columns.
+func BatchInsert(db *sqlx.DB, table string, rows []map[string]any, chunkSize int) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	columns, err := rowSetColumns(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	return batchInsert(db, table, "INSERT", columns, rows, chunkSize, "")
+}
+
+// Seed loads reference rows exactly once: rows that collide with an existing
+// row on conflictColumns are skipped with INSERT ... ON CONFLICT DO NOTHING,
+// so Seed can run on every startup right after Open. With no conflict
+// columns any uniqueness conflict skips the row. Every column is checked
+// against the table before anything is inserted.
+func Seed(db *sqlx.DB, table string, rows []map[string]any, conflictColumns []string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	columns, err := rowSetColumns(rows)
+	if err != nil {
+		return err
+	}
+
+	if err := checkTableColumns(db, table, columns, conflictColumns); err != nil {
+		return err
+	}
+
+	target := ""
+	if len(conflictColumns) > 0 {
+		target = "(" + quoteIdents(conflictColumns) + ")"
+	}
+
+	if _, err := batchInsert(db, table, "INSERT", columns, rows, 0, " ON CONFLICT"+target+" DO NOTHING"); err != nil {
+		return fmt.Errorf("seed %s: %w", table, err)
+	}
+
+	return nil
+}
+
+// UpsertResult describes one row written by Upsert: its rowid and whether
+// it was inserted rather than updated.
+type UpsertResult struct {
+	RowID    int64
+	Inserted bool
+}
+
+// Upsert inserts rows into table, updating updateColumns from the new row
+// when a row conflicts with an existing one on conflictColumns, and returns
+// the rowid of every row in input order together with whether it was
+// inserted or updated. Before each row it looks up an existing row on
+// conflictColumns, which needs an index on them to stay fast; a NULL in a
+// conflict column never matches, as in a UNIQUE constraint. It relies on
+// RETURNING rowid and so needs SQLite 3.35.0 or later and a rowid table;
+// WITHOUT ROWID tables are rejected. All rows are written in one
+// transaction.
+func Upsert(db *sqlx.DB, table string, rows []map[string]any, conflictColumns, updateColumns []string) ([]UpsertResult, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	if len(conflictColumns) == 0 {
+		return nil, errors.New("conflict columns are required")
+	}
+	if len(updateColumns) == 0 {
+		return nil, errors.New("update columns are required; use Seed to skip conflicting rows")
+	}
+
+	version, err := sqliteVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if !versionAtLeast(version, 3, 35, 0) {
+		return nil, fmt.Errorf("upsert with RETURNING requires SQLite 3.35.0, running %d.%d.%d", version[0], version[1], version[2])
+	}
+
+	columns, err := rowSetColumns(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTableColumns(db, table, columns, conflictColumns, updateColumns); err != nil {
+		return nil, err
+	}
+	createSQL, err := tableSQL(db, table)
+	if err != nil {
+		return nil, err
+	}
+	if hasTableOption(createSQL, "WITHOUT ROWID") {
+		return nil, fmt.Errorf("upsert into %s: WITHOUT ROWID tables have no rowid to return", table)
+	}
+	index := make(map[string]int, len(columns))
+	for i, column := range columns {
+		index[strings.ToLower(column)] = i
+	}
+	keys := make([]int, len(conflictColumns))
+	for i, column := range conflictColumns {
+		j, ok := index[strings.ToLower(column)]
+		if !ok {
+			return nil, fmt.Errorf("upsert into %s: rows have no value for conflict column %s", table, column)
+		}
+		keys[i] = j
+	}
+
+	sets := make([]string, len(updateColumns))
+	for i, column := range updateColumns {
+		sets[i] = fmt.Sprintf("%s = excluded.%s", quoteIdent(column), quoteIdent(column))
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT(%s) DO UPDATE SET %s RETURNING rowid",
+		quoteIdent(table), quoteIdents(columns), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "),
+		quoteIdents(conflictColumns), strings.Join(sets, ", "))
+	matches := make([]string, len(conflictColumns))
+	for i, column := range conflictColumns {
+		matches[i] = quoteIdent(column) + " = ?"
+	}
+	existsQuery := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s)", quoteIdent(table), strings.Join(matches, " AND "))
+
+	results := make([]UpsertResult, 0, len(rows))
+	err = WithTx(db, func(tx *sqlx.Tx) error {
+		stmt, err := tx.Preparex(query)
+		if err != nil {
+			return fmt.Errorf("prepare upsert into %s: %w", table, err)
+		}
+		defer stmt.Close()
+		existsStmt, err := tx.Preparex(existsQuery)
+		if err != nil {
+			return fmt.Errorf("prepare upsert into %s: %w", table, err)
+		}
+		defer existsStmt.Close()
+
+		args := make([]any, len(columns))
+		keyArgs := make([]any, len(keys))
+		for i, row := range rows {
+			for j, column := range columns {
+				args[j] = row[column]
+			}
+			for j, k := range keys {
+				keyArgs[j] = args[k]
+			}
+			var exists bool
+			if err := existsStmt.Get(&exists, keyArgs...); err != nil {
+				return fmt.Errorf("look up row %d in %s: %w", i, table, err)
+			}
+			var id int64
+			if err := stmt.Get(&id, args...); err != nil {
+				return fmt.Errorf("upsert row %d into %s: %w", i, table, err)
+			}
+			results = append(results, UpsertResult{RowID: id, Inserted: !exists})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// checkTableColumns fails when table is missing or lacks any of the given
+// column lists.
+func checkTableColumns(db *sqlx.DB, table string, columnLists ...[]string) error {
+	info, err := GetTableColumns(db, table)
+	if err != nil {
+		return err
+	}
+	if len(info) == 0 {
+		return &ValidationError{Err: ErrTableMissing, Table: table}
+	}
+	known := make(map[string]bool, len(info))
+	for _, column := range info {
+		known[strings.ToLower(column.Name)] = true
+	}
+	for _, columns := range columnLists {
+		for _, column := range columns {
+			if !known[strings.ToLower(column)] {
+				return &ValidationError{Err: ErrColumnMissing, Table: table, Column: column}
+			}
+		}
+	}
+
+	return nil
+}
+
+func quoteIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdent(name)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// rowSetColumns returns the sorted columns of the first row and checks that
+// every other row has exactly the same columns.
+func rowSetColumns(rows []map[string]any) ([]string, error) {
+	columns := make([]string, 0, len(rows[0]))
+	for column := range rows[0] {
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("rows have no columns")
+	}
+	sort.Strings(columns)
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("row %d: expected %d columns, got %d", i, len(columns), len(row))
+		}
+		for _, column := range columns {
+			if _, ok := row[column]; !ok {
+				return nil, fmt.Errorf("row %d: missing column %s", i, column)
+			}
+		}
+	}
+
+	return columns, nil
+}
+
+// batchInsert writes rows with multi-row statements made of verb, the column
+// list, the VALUES tuples and suffix.
+func batchInsert(db *sqlx.DB, table, verb string, columns []string, rows []map[string]any, chunkSize int, suffix string) (int64, error) {
+	perStatement := maxBoundParams / len(columns)
+	if perStatement == 0 {
+		return 0, fmt.Errorf("too many columns for a single insert: %d", len(columns))
+	}
+	if chunkSize > 0 && chunkSize < perStatement {
+		perStatement = chunkSize
+	}
+
+	prefix := fmt.Sprintf("%s INTO %s (%s) VALUES ", verb, quoteIdent(table), quoteIdents(columns))
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var inserted int64
+	for start := 0; start < len(rows); start += perStatement {
+		end := min(start+perStatement, len(rows))
+		chunk := rows[start:end]
+
+		values := make([]string, len(chunk))
+		args := make([]any, 0, len(chunk)*len(columns))
+		for i, row := range chunk {
+			values[i] = placeholder
+			for _, column := range columns {
+				args = append(args, row[column])
+			}
+		}
+
+		result, err := tx.Exec(prefix+strings.Join(values, ", ")+suffix, args...)
+		if err != nil {
+			return 0, fmt.Errorf("insert rows %d-%d into %s: %w", start, end-1, table, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("read rows affected: %w", err)
+		}
+		inserted += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return inserted, nil
+}
@@ -0,0 +1,140 @@
+package sqlite_base
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBatchInsert_SplitsIntoChunks(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT NOT NULL, weight INTEGER NOT NULL)")
+
+	rows := make([]map[string]any, 2500)
+	for i := range rows {
+		rows[i] = map[string]any{"kind": "click", "weight": i}
+	}
+
+	inserted, err := BatchInsert(db, "events", rows, 1000)
+	if err != nil {
+		t.Fatalf("batch insert failed: %v", err)
+	}
+	if inserted != int64(len(rows)) {
+		t.Fatalf("expected %d rows inserted, got %d", len(rows), inserted)
+	}
+
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM events"); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if count != len(rows) {
+		t.Fatalf("expected %d rows, got %d", len(rows), count)
+	}
+}
+
+func TestBatchInsert_EmptyAndInvalidRows(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT NOT NULL)")
+
+	inserted, err := BatchInsert(db, "events", nil, 10)
+	if err != nil || inserted != 0 {
+		t.Fatalf("expected empty insert to be a noop, got %d, %v", inserted, err)
+	}
+
+	rows := []map[string]any{{"kind": "a"}, {"name": "b"}}
+	if _, err := BatchInsert(db, "events", rows, 10); err == nil {
+		t.Fatal("expected error for mismatched columns")
+	}
+
+	rows = []map[string]any{{"kind": "a"}, {"kind": nil}}
+	if _, err := BatchInsert(db, "events", rows, 1); err == nil {
+		t.Fatal("expected constraint error")
+	}
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM events"); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected failed batch to roll back, got %d rows", count)
+	}
+}
+
+func TestSeed_IsIdempotent(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE roles (id INTEGER PRIMARY KEY, code TEXT NOT NULL UNIQUE, label TEXT NOT NULL)")
+
+	rows := []map[string]any{
+		{"code": "admin", "label": "Administrator"},
+		{"code": "viewer", "label": "Viewer"},
+	}
+	for i := 0; i < 2; i++ {
+		if err := Seed(db, "roles", rows, []string{"code"}); err != nil {
+			t.Fatalf("seed run %d failed: %v", i+1, err)
+		}
+	}
+
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM roles"); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 roles, got %d", count)
+	}
+
+	if err := Seed(db, "roles", nil, []string{"code"}); err != nil {
+		t.Fatalf("seed with no rows failed: %v", err)
+	}
+	if err := Seed(db, "roles", []map[string]any{{"name": "x"}}, []string{"code"}); err == nil {
+		t.Fatal("expected unknown column to fail")
+	}
+	if err := Seed(db, "roles", rows, []string{"slug"}); err == nil {
+		t.Fatal("expected unknown conflict column to fail")
+	}
+}
+
+func TestUpsert_ReturnsRowids(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE products (id INTEGER PRIMARY KEY, sku TEXT NOT NULL UNIQUE, price INTEGER NOT NULL)",
+		"INSERT INTO products (id, sku, price) VALUES (7, 'a', 100)",
+	)
+
+	results, err := Upsert(db, "products", []map[string]any{
+		{"sku": "a", "price": 150},
+		{"sku": "b", "price": 200},
+		{"sku": "b", "price": 250},
+	}, []string{"sku"}, []string{"price"})
+	if err != nil {
+		t.Fatalf("upsert failed: %v", err)
+	}
+	if len(results) != 3 || results[0] != (UpsertResult{RowID: 7}) || !results[1].Inserted || results[1].RowID == 7 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if results[2] != (UpsertResult{RowID: results[1].RowID}) {
+		t.Fatalf("expected the repeated key to update the inserted row, got %+v", results[2])
+	}
+
+	var price int
+	if err := db.Get(&price, "SELECT price FROM products WHERE sku = 'a'"); err != nil {
+		t.Fatalf("select failed: %v", err)
+	}
+	if price != 150 {
+		t.Fatalf("expected updated price 150, got %d", price)
+	}
+
+	if _, err := Upsert(db, "products", []map[string]any{{"sku": "c", "price": 1}}, []string{"sku"}, nil); err == nil {
+		t.Fatal("expected missing update columns to fail")
+	}
+	if _, err := Upsert(db, "products", []map[string]any{{"sku": "c", "price": 1}}, []string{"sku"}, []string{"cost"}); err == nil {
+		t.Fatal("expected unknown update column to fail")
+	}
+
+	db.MustExec("CREATE TABLE rates (code TEXT PRIMARY KEY, rate REAL) WITHOUT ROWID")
+	_, err = Upsert(db, "rates", []map[string]any{{"code": "eur", "rate": 1.1}}, []string{"code"}, []string{"rate"})
+	if err == nil || !strings.Contains(err.Error(), "WITHOUT ROWID") {
+		t.Fatalf("expected WITHOUT ROWID table to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package sqlite_base
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type BlobStat struct {
+	Table    string
+	Column   string
+	Rows     int64
+	AvgBytes float64
+	MaxBytes int64
+}
+
+// LargeBlobColumns reports BLOB-affinity columns whose average or largest
+// stored blob exceeds thresholdBytes, as candidates for external storage.
+func LargeBlobColumns(db *sqlx.DB, thresholdBytes int64) ([]BlobStat, error) {
+	tables, err := ListTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := []BlobStat{}
+	for _, table := range tables {
+		columns, err := GetTableColumns(db, table)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, column := range columns {
+			if typeAffinity(column.Type) != "BLOB" {
+				continue
+			}
+
+			stat := BlobStat{Table: table, Column: column.Name}
+			query := fmt.Sprintf(`SELECT COUNT(*), COALESCE(AVG(length(%[1]s)), 0), COALESCE(MAX(length(%[1]s)), 0) FROM %[2]s WHERE typeof(%[1]s) = 'blob'`, quoteIdent(column.Name), quoteIdent(table))
+			if err := db.QueryRowx(query).Scan(&stat.Rows, &stat.AvgBytes, &stat.MaxBytes); err != nil {
+				return nil, fmt.Errorf("measure %s.%s: %w", table, column.Name, err)
+			}
+			if stat.AvgBytes > float64(thresholdBytes) || stat.MaxBytes > thresholdBytes {
+				stats = append(stats, stat)
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// DatabaseSize returns the size of the main database file in bytes, computed
+// as page_count * page_size. Pages on the freelist are included; use
+// InUseSize to exclude them.
+func DatabaseSize(db *sqlx.DB) (int64, error) {
+	var size int64
+	if err := db.Get(&size, "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()"); err != nil {
+		return 0, fmt.Errorf("query database size: %w", err)
+	}
+
+	return size, nil
+}
+
+// InUseSize returns the bytes occupied by pages holding data, i.e.
+// DatabaseSize minus the freelist that VACUUM would reclaim.
+func InUseSize(db *sqlx.DB) (int64, error) {
+	var size int64
+	err := db.Get(&size, "SELECT (page_count - freelist_count) * page_size FROM pragma_page_count(), pragma_freelist_count(), pragma_page_size()")
+	if err != nil {
+		return 0, fmt.Errorf("query in-use size: %w", err)
+	}
+
+	return size, nil
+}
+
+// TableRowCounts returns the number of rows in every user table. Each count
+// is a full COUNT(*), so this scans the whole database.
+func TableRowCounts(db *sqlx.DB) (map[string]int64, error) {
+	tables, err := ListTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		n, err := Count(db, table, "")
+		if err != nil {
+			return nil, err
+		}
+		counts[table] = n
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,65 @@
+package sqlite_base
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLargeBlobColumns_ReportsColumnsAboveThreshold(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE files (id INTEGER PRIMARY KEY, name TEXT, content BLOB, thumbnail BLOB)",
+	)
+	db.MustExec("INSERT INTO files (name, content, thumbnail) VALUES (?, ?, ?)", "a.bin", bytes.Repeat([]byte{1}, 64*1024), []byte{1, 2, 3})
+	db.MustExec("INSERT INTO files (name, content, thumbnail) VALUES (?, ?, ?)", "b.bin", []byte{1}, []byte{4, 5})
+
+	stats, err := LargeBlobColumns(db, 16*1024)
+	if err != nil {
+		t.Fatalf("large blob columns failed: %v", err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("expected one reported column, got %+v", stats)
+	}
+
+	stat := stats[0]
+	if stat.Table != "files" || stat.Column != "content" || stat.Rows != 2 || stat.MaxBytes != 64*1024 {
+		t.Fatalf("unexpected stat: %+v", stat)
+	}
+}
+
+func TestDatabaseSizeAndRowCounts(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE a (id INTEGER PRIMARY KEY, body TEXT)",
+		"CREATE TABLE b (id INTEGER PRIMARY KEY)",
+		"WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 200) INSERT INTO a (body) SELECT printf('%0500d', i) FROM n",
+		"INSERT INTO b DEFAULT VALUES",
+	)
+
+	counts, err := TableRowCounts(db)
+	if err != nil {
+		t.Fatalf("table row counts failed: %v", err)
+	}
+	if counts["a"] != 200 || counts["b"] != 1 {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+
+	before, err := DatabaseSize(db)
+	if err != nil {
+		t.Fatalf("database size failed: %v", err)
+	}
+	db.MustExec("DELETE FROM a")
+	total, err := DatabaseSize(db)
+	if err != nil {
+		t.Fatalf("database size failed: %v", err)
+	}
+	inUse, err := InUseSize(db)
+	if err != nil {
+		t.Fatalf("in-use size failed: %v", err)
+	}
+	if total != before || inUse >= total {
+		t.Fatalf("expected freed pages on the freelist: before=%d total=%d inUse=%d", before, total, inUse)
+	}
+}
@@ -0,0 +1,38 @@
+package sqlite_base
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Changes returns SQLite's changes() for whichever pooled connection runs it.
+// changes() and total_changes() are scoped to a single SQLite connection, so
+// Changes and TotalChanges are only meaningful when the pool is limited to one
+// connection (db.SetMaxOpenConns(1)). Otherwise use ConnChanges and
+// ConnTotalChanges on the *sqlx.Conn that ran the write, right after the write.
+func Changes(db *sqlx.DB) (int64, error) {
+	return queryCounter(context.Background(), db, "changes")
+}
+
+func TotalChanges(db *sqlx.DB) (int64, error) {
+	return queryCounter(context.Background(), db, "total_changes")
+}
+
+func ConnChanges(ctx context.Context, conn *sqlx.Conn) (int64, error) {
+	return queryCounter(ctx, conn, "changes")
+}
+
+func ConnTotalChanges(ctx context.Context, conn *sqlx.Conn) (int64, error) {
+	return queryCounter(ctx, conn, "total_changes")
+}
+
+func queryCounter(ctx context.Context, q sqlx.QueryerContext, fn string) (int64, error) {
+	var n int64
+	if err := sqlx.GetContext(ctx, q, &n, "SELECT "+fn+"()"); err != nil {
+		return 0, fmt.Errorf("query %s: %w", fn, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,67 @@
+package sqlite_base
+
+import (
+	"context"
+	"testing"
+)
+
+func TestConnChanges_ReportsRowsFromLastStatement(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t)
+
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		t.Fatalf("checkout connection failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	stmts := []string{
+		"CREATE TABLE items (id INTEGER PRIMARY KEY, qty INTEGER NOT NULL)",
+		"INSERT INTO items (qty) VALUES (1), (2), (3), (4)",
+		"UPDATE items SET qty = qty + 10 WHERE qty > 1",
+	}
+	for _, stmt := range stmts {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("exec %q failed: %v", stmt, err)
+		}
+	}
+
+	changes, err := ConnChanges(ctx, conn)
+	if err != nil {
+		t.Fatalf("changes failed: %v", err)
+	}
+	if changes != 3 {
+		t.Fatalf("expected 3 changes, got %d", changes)
+	}
+
+	total, err := ConnTotalChanges(ctx, conn)
+	if err != nil {
+		t.Fatalf("total changes failed: %v", err)
+	}
+	if total != 7 {
+		t.Fatalf("expected 7 total changes, got %d", total)
+	}
+}
+
+func TestChanges_SingleConnectionPool(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE items (id INTEGER PRIMARY KEY, qty INTEGER NOT NULL)",
+		"INSERT INTO items (qty) VALUES (1), (2)",
+	)
+
+	changes, err := Changes(db)
+	if err != nil {
+		t.Fatalf("changes failed: %v", err)
+	}
+	if changes != 2 {
+		t.Fatalf("expected 2 changes, got %d", changes)
+	}
+
+	if _, err := TotalChanges(db); err != nil {
+		t.Fatalf("total changes failed: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package sqlite_base
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Check asserts that Table has a CHECK constraint. Expr is the expression
+// inside CHECK(...), compared token by token ignoring whitespace, identifier
+// quoting and the case of keywords and identifiers. When Name is set the
+// constraint must be declared as CONSTRAINT <Name> CHECK(...); otherwise any
+// CHECK with a matching expression satisfies it.
+type Check struct {
+	Table string
+	Name  string
+	Expr  string
+}
+
+type checkConstraint struct {
+	Name string
+	Expr string
+}
+
+func validateCheck(db *sqlx.DB, check Check) error {
+	createSQL, err := tableSQL(db, check.Table)
+	if err != nil {
+		return err
+	}
+
+	want := canonicalExpr(check.Expr)
+	for _, constraint := range checkConstraints(createSQL) {
+		if check.Name == "" {
+			if canonicalExpr(constraint.Expr) == want {
+				return nil
+			}
+			continue
+		}
+		if !strings.EqualFold(constraint.Name, check.Name) {
+			continue
+		}
+		if canonicalExpr(constraint.Expr) != want {
+			return fmt.Errorf("table %s: check constraint %s expected %s, got %s", check.Table, check.Name, check.Expr, constraint.Expr)
+		}
+		return nil
+	}
+
+	if check.Name != "" {
+		return fmt.Errorf("table %s: check constraint %s is missing", check.Table, check.Name)
+	}
+	return fmt.Errorf("table %s: check constraint %s is missing", check.Table, check.Expr)
+}
+
+// checkConstraints extracts the CHECK constraints of a CREATE TABLE statement,
+// column and table level alike, since no PRAGMA reports them.
+func checkConstraints(createSQL string) []checkConstraint {
+	tokens := sqlTokens(createSQL)
+
+	var checks []checkConstraint
+	for i := 0; i+1 < len(tokens); i++ {
+		if !tokens[i].word || !strings.EqualFold(tokens[i].text, "CHECK") || tokens[i+1].text != "(" {
+			continue
+		}
+
+		depth := 0
+		end := -1
+		for j := i + 1; j < len(tokens) && end < 0; j++ {
+			switch tokens[j].text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+				if depth == 0 {
+					end = j
+				}
+			}
+		}
+		if end < 0 {
+			break
+		}
+
+		check := checkConstraint{Expr: strings.TrimSpace(createSQL[tokens[i+1].end:tokens[end].start])}
+		if i >= 2 && tokens[i-2].word && strings.EqualFold(tokens[i-2].text, "CONSTRAINT") {
+			check.Name = tokens[i-1].text
+		}
+		checks = append(checks, check)
+		i = end
+	}
+
+	return checks
+}
+
+// canonicalExpr rebuilds an expression from its tokens so that spacing and
+// identifier quoting do not affect comparisons. String literals keep their
+// case.
+func canonicalExpr(expr string) string {
+	return strings.Join(canonicalTokens(expr), " ")
+}
+
+func canonicalTokens(stmt string) []string {
+	tokens := sqlTokens(stmt)
+	parts := make([]string, len(tokens))
+	for i, token := range tokens {
+		switch {
+		case token.literal:
+			parts[i] = "'" + token.text + "'"
+		case token.word:
+			parts[i] = strings.ToLower(token.text)
+		default:
+			parts[i] = token.text
+		}
+	}
+
+	return parts
+}
+
+type sqlToken struct {
+	text    string
+	word    bool
+	literal bool
+	start   int
+	end     int
+}
+
+// sqlTokens splits a statement into identifiers, string literals and single
+// punctuation characters, skipping whitespace and comments. Quoted
+// identifiers are returned unquoted so they compare like bare ones.
+func sqlTokens(stmt string) []sqlToken {
+	var tokens []sqlToken
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(stmt[i:], "--"):
+			end := strings.IndexByte(stmt[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+		case strings.HasPrefix(stmt[i:], "/*"):
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			j := i + 1
+			for j < len(stmt) {
+				if stmt[j] == closing {
+					if closing != ']' && j+1 < len(stmt) && stmt[j+1] == closing {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			end := min(j+1, len(stmt))
+			text := stmt[i+1 : min(j, len(stmt))]
+			if c != '[' {
+				text = strings.ReplaceAll(text, string([]byte{c, c}), string(c))
+			}
+			tokens = append(tokens, sqlToken{text: text, word: c != '\'', literal: c == '\'', start: i, end: end})
+			i = end
+		case isIdentByte(c):
+			j := i
+			for j < len(stmt) && isIdentByte(stmt[j]) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: stmt[i:j], word: true, start: i, end: j})
+			i = j
+		default:
+			tokens = append(tokens, sqlToken{text: stmt[i : i+1], start: i, end: i + 1})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
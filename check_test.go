@@ -0,0 +1,61 @@
+package sqlite_base
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckConstraints_Parse(t *testing.T) {
+	t.Parallel()
+
+	createSQL := `CREATE TABLE accounts (
+		id INTEGER PRIMARY KEY,
+		note TEXT DEFAULT 'CHECK (fake)', -- CHECK (comment)
+		balance INTEGER NOT NULL CHECK (balance >= 0),
+		kind TEXT,
+		CONSTRAINT "valid kind" CHECK (kind IN ('a', 'b') AND length(kind) = 1)
+	)`
+
+	want := []checkConstraint{
+		{Expr: "balance >= 0"},
+		{Name: "valid kind", Expr: "kind IN ('a', 'b') AND length(kind) = 1"},
+	}
+	if got := checkConstraints(createSQL); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected checks:\n got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestValidateSchema_Checks(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, `CREATE TABLE accounts (
+		id INTEGER PRIMARY KEY,
+		balance INTEGER NOT NULL CHECK (balance >= 0),
+		kind TEXT,
+		CONSTRAINT kind_valid CHECK (kind IN ('a', 'b'))
+	)`)
+
+	err := ValidateSchema(db, Schema{Checks: []Check{
+		{Table: "accounts", Expr: "BALANCE >= 0"},
+		{Table: "accounts", Name: "kind_valid", Expr: "kind IN ('a','b')"},
+	}})
+	if err != nil {
+		t.Fatalf("validate checks failed: %v", err)
+	}
+
+	tests := []struct {
+		check Check
+		want  string
+	}{
+		{Check{Table: "accounts", Expr: "balance > 0"}, "table accounts: check constraint balance > 0 is missing"},
+		{Check{Table: "accounts", Name: "positive"}, "table accounts: check constraint positive is missing"},
+		{Check{Table: "accounts", Name: "kind_valid", Expr: "kind = 'a'"}, "table accounts: check constraint kind_valid expected kind = 'a', got kind IN ('a', 'b')"},
+	}
+	for _, tt := range tests {
+		err := ValidateSchema(db, Schema{Checks: []Check{tt.check}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("expected %q, got %v", tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package sqlite_base
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// connector opens pooled connections for Open without registering a named
+// database/sql driver, so any number of differently configured databases can
+// coexist in one process.
+type connector struct {
+	dsn     string
+	driver  *sqlite3.SQLiteDriver
+	metrics *statementMetrics
+	pragmas []string
+	attach  []attachment
+	hook    func(*sqlite3.SQLiteConn) error
+	collate map[string]func(a, b string) int
+	funcs   []Function
+	timeout time.Duration
+	busy    func(attempt int) bool
+
+	mu  sync.Mutex
+	key string
+}
+
+func newConnector(dsn string, config Config) (*connector, error) {
+	pragmas, err := connectionPragmas(config.Pragmas)
+	if err != nil {
+		return nil, err
+	}
+	if config.ReadUncommitted {
+		pragmas = append(pragmas, "PRAGMA read_uncommitted = 1")
+	}
+	if config.MmapSize < 0 {
+		return nil, fmt.Errorf("mmap size must not be negative, got %d", config.MmapSize)
+	}
+	if config.MmapSize > 0 {
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA mmap_size = %d", config.MmapSize))
+	}
+
+	attachments, err := connectionAttachments(config.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &connector{
+		dsn:     dsn,
+		driver:  &sqlite3.SQLiteDriver{},
+		pragmas: pragmas,
+		attach:  attachments,
+		hook:    config.ConnectHook,
+		collate: config.Collations,
+		funcs:   config.Functions,
+		timeout: config.DefaultQueryTimeout,
+		busy:    config.BusyHandler,
+		key:     config.EncryptionKey,
+	}
+	if config.Metrics != nil {
+		c.metrics = &statementMetrics{collector: config.Metrics}
+	}
+
+	return c, nil
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	sqliteConn := conn.(*sqlite3.SQLiteConn)
+	if err := c.configure(sqliteConn); err != nil {
+		_ = sqliteConn.Close()
+		return nil, err
+	}
+	if c.metrics == nil && c.timeout <= 0 && c.busy == nil {
+		return sqliteConn, nil
+	}
+
+	return &instrumentedConn{SQLiteConn: sqliteConn, metrics: c.metrics, timeout: c.timeout, busy: c.busy}, nil
+}
+
+// configure applies the per-connection settings that cannot be expressed in
+// the DSN: the encryption key first, then collations, functions, pragmas and
+// attachments, and finally the caller's ConnectHook.
+func (c *connector) configure(conn *sqlite3.SQLiteConn) error {
+	if key := c.currentKey(); key != "" {
+		if err := applyKey(conn, key); err != nil {
+			return err
+		}
+	}
+	for name, cmp := range c.collate {
+		if err := conn.RegisterCollation(name, cmp); err != nil {
+			return fmt.Errorf("register collation %s: %w", name, err)
+		}
+	}
+	for _, fn := range c.funcs {
+		if err := conn.RegisterFunc(fn.Name, fn.Impl, fn.Pure); err != nil {
+			return fmt.Errorf("register function %s: %w", fn.Name, err)
+		}
+	}
+	for _, stmt := range c.pragmas {
+		if _, err := conn.Exec(stmt, nil); err != nil {
+			return fmt.Errorf("apply connection pragma: %w", err)
+		}
+	}
+	for _, a := range c.attach {
+		if err := attachConn(conn, a); err != nil {
+			return err
+		}
+	}
+	if c.hook != nil {
+		if err := c.hook(conn); err != nil {
+			return fmt.Errorf("connect hook: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Driver returns the connector itself so that helpers such as Stats can find
+// the connector behind a *sql.DB through db.Driver().
+func (c *connector) Driver() driver.Driver {
+	return c
+}
+
+func (c *connector) Open(name string) (driver.Conn, error) {
+	return c.driver.Open(name)
+}
+
+func (c *connector) currentKey() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.key
+}
+
+func (c *connector) setKey(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.key = key
+}
+
+// instrumentedConn applies the default statement timeout and busy handler
+// and records statement metrics around the driver's context-aware methods,
+// including the statements it prepares.
+type instrumentedConn struct {
+	*sqlite3.SQLiteConn
+	metrics *statementMetrics
+	timeout time.Duration
+	busy    func(attempt int) bool
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.exec(ctx, query, func(ctx context.Context) (driver.Result, error) {
+		return c.SQLiteConn.ExecContext(ctx, query, args)
+	})
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.query(ctx, query, func(ctx context.Context) (driver.Rows, error) {
+		return c.SQLiteConn.QueryContext(ctx, query, args)
+	})
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	stmt, err := c.SQLiteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentedStmt{SQLiteStmt: stmt.(*sqlite3.SQLiteStmt), conn: c, query: query}, nil
+}
+
+func (c *instrumentedConn) exec(ctx context.Context, query string, fn func(context.Context) (driver.Result, error)) (driver.Result, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	var result driver.Result
+	err := c.retryBusy(ctx, query, func() (err error) {
+		result, err = fn(ctx)
+		return err
+	})
+	if c.metrics != nil {
+		c.metrics.observe(StatementExec, query, start, err)
+	}
+
+	return result, err
+}
+
+func (c *instrumentedConn) query(ctx context.Context, query string, fn func(context.Context) (driver.Rows, error)) (driver.Rows, error) {
+	ctx, cancel := c.withTimeout(ctx)
+
+	start := time.Now()
+	var rows driver.Rows
+	err := c.retryBusy(ctx, query, func() (err error) {
+		rows, err = fn(ctx)
+		return err
+	})
+	if c.metrics != nil {
+		c.metrics.observe(StatementQuery, query, start, err)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The driver watches ctx while rows are read, so the timeout is only
+	// released once the caller closes them.
+	return &timeoutRows{SQLiteRows: rows.(*sqlite3.SQLiteRows), cancel: cancel}, nil
+}
+
+// instrumentedStmt gives prepared statements, including those of Preparex
+// and StmtCache, the same timeout, busy handling and metrics as statements
+// run directly on the connection.
+type instrumentedStmt struct {
+	*sqlite3.SQLiteStmt
+	conn  *instrumentedConn
+	query string
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.exec(ctx, s.query, func(ctx context.Context) (driver.Result, error) {
+		return s.SQLiteStmt.ExecContext(ctx, args)
+	})
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.query(ctx, s.query, func(ctx context.Context) (driver.Rows, error) {
+		return s.SQLiteStmt.QueryContext(ctx, args)
+	})
+}
+
+// retryBusy runs fn again for as long as it fails with SQLITE_BUSY and the
+// busy handler asks for another attempt. A query holding several statements
+// is never retried, since the statements before the busy one have already
+// run and would be applied twice.
+func (c *instrumentedConn) retryBusy(ctx context.Context, query string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if c.busy == nil || !IsBusy(err) || ctx.Err() != nil {
+			return err
+		}
+		if attempt == 1 && len(splitStatements(query)) > 1 {
+			return err
+		}
+		if !c.busy(attempt) {
+			return err
+		}
+	}
+}
+
+// withTimeout bounds ctx by the default timeout unless the caller already set
+// a deadline.
+func (c *instrumentedConn) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+type timeoutRows struct {
+	*sqlite3.SQLiteRows
+	cancel context.CancelFunc
+}
+
+func (r *timeoutRows) Close() error {
+	defer r.cancel()
+
+	return r.SQLiteRows.Close()
+}
@@ -0,0 +1,329 @@
+package sqlite_base
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestOpen_ConnectHook(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{
+		Path: filepath.Join(t.TempDir(), "app.sqlite"),
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("reverse", func(s string) string {
+				runes := []rune(s)
+				for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+					runes[i], runes[j] = runes[j], runes[i]
+				}
+				return string(runes)
+			}, true)
+		},
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	var got string
+	if err := db.Get(&got, "SELECT reverse('sqlite')"); err != nil {
+		t.Fatalf("custom function failed: %v", err)
+	}
+	if got != "etilqs" {
+		t.Fatalf("expected etilqs, got %s", got)
+	}
+}
+
+func TestOpen_ConnectHookError(t *testing.T) {
+	t.Parallel()
+
+	hookErr := errors.New("boom")
+	_, err := Open(Config{
+		Path:        filepath.Join(t.TempDir(), "app.sqlite"),
+		ConnectHook: func(*sqlite3.SQLiteConn) error { return hookErr },
+	})
+	if !errors.Is(err, hookErr) || !strings.Contains(err.Error(), "connect hook") {
+		t.Fatalf("expected hook error, got %v", err)
+	}
+}
+
+func TestOpen_RepeatedWithHooksDoesNotPanic(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for i := 0; i < 100; i++ {
+		db, err := Open(Config{
+			Path:        filepath.Join(dir, "app.sqlite"),
+			Pragmas:     map[string]string{"temp_store": "MEMORY"},
+			ConnectHook: func(*sqlite3.SQLiteConn) error { return nil },
+		})
+		if err != nil {
+			t.Fatalf("open %d failed: %v", i, err)
+		}
+		if err := db.Close(); err != nil {
+			t.Fatalf("close %d failed: %v", i, err)
+		}
+	}
+}
+
+func TestOpen_DefaultQueryTimeout(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{
+		Path:                filepath.Join(t.TempDir(), "app.sqlite"),
+		DefaultQueryTimeout: 100 * time.Millisecond,
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("sleep_ms", func(ms int64) int64 {
+				time.Sleep(time.Duration(ms) * time.Millisecond)
+				return ms
+			}, false)
+		},
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	start := time.Now()
+	var total int64
+	err = db.Get(&total, "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000) SELECT SUM(sleep_ms(20)) FROM n")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("query was not interrupted promptly, took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.GetContext(ctx, &total, "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 10) SELECT SUM(sleep_ms(20)) FROM n"); err != nil {
+		t.Fatalf("expected caller deadline to take precedence, got: %v", err)
+	}
+
+	rows, err := db.Queryx("WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 3) SELECT i FROM n")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	if err := rows.Err(); err != nil || count != 3 {
+		t.Fatalf("expected 3 rows, got %d (err %v)", count, err)
+	}
+	_ = rows.Close()
+
+	stmt, err := db.Preparex("WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < ?) SELECT SUM(sleep_ms(20)) FROM n")
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	defer stmt.Close()
+	start = time.Now()
+	if err := stmt.Get(&total, 1000); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded for a prepared statement, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("prepared statement was not interrupted promptly, took %s", elapsed)
+	}
+	if err := stmt.Get(&total, 2); err != nil || total != 40 {
+		t.Fatalf("expected the prepared statement to be reusable, got %d, %v", total, err)
+	}
+}
+
+func TestOpen_Collations(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{
+		Path: filepath.Join(t.TempDir(), "app.sqlite"),
+		Collations: map[string]func(a, b string) int{
+			"by_length": func(a, b string) int { return len([]rune(a)) - len([]rune(b)) },
+		},
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	db.MustExec("CREATE TABLE words (w TEXT)")
+	db.MustExec("INSERT INTO words VALUES ('ภาษาไทย'), ('ab'), ('abc')")
+
+	var words []string
+	if err := db.Select(&words, "SELECT w FROM words ORDER BY w COLLATE by_length"); err != nil {
+		t.Fatalf("select failed: %v", err)
+	}
+	if strings.Join(words, ",") != "ab,abc,ภาษาไทย" {
+		t.Fatalf("unexpected order: %v", words)
+	}
+}
+
+func TestOpen_Functions(t *testing.T) {
+	t.Parallel()
+
+	slugify := func(s string) string { return strings.ReplaceAll(strings.ToLower(s), " ", "-") }
+	db, err := Open(Config{
+		Path:      filepath.Join(t.TempDir(), "app.sqlite"),
+		Functions: []Function{{Name: "slugify", Impl: slugify, Pure: true}},
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	var slug string
+	if err := db.Get(&slug, "SELECT slugify('Hello SQLite World')"); err != nil {
+		t.Fatalf("select failed: %v", err)
+	}
+	if slug != "hello-sqlite-world" {
+		t.Fatalf("unexpected slug %q", slug)
+	}
+
+	_, err = Open(Config{
+		Path:      filepath.Join(t.TempDir(), "app.sqlite"),
+		Functions: []Function{{Name: "broken", Impl: func(chan int) int { return 0 }}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "register function broken") {
+		t.Fatalf("expected registration error at open, got %v", err)
+	}
+}
+
+func TestOpen_BusyHandler(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var attempts []int
+	var release func()
+	db, err := Open(Config{
+		Path: filepath.Join(t.TempDir(), "app.sqlite"),
+		BusyHandler: func(attempt int) bool {
+			attempts = append(attempts, attempt)
+			if attempt == 3 {
+				release()
+			}
+			return attempt < 5
+		},
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	var timeout int
+	if err := db.Get(&timeout, "PRAGMA busy_timeout"); err != nil {
+		t.Fatalf("read busy timeout failed: %v", err)
+	}
+	if timeout != 0 {
+		t.Fatalf("expected busy timeout to be disabled, got %d", timeout)
+	}
+
+	lock := func() {
+		conn, err := db.Connx(ctx)
+		if err != nil {
+			t.Fatalf("checkout failed: %v", err)
+		}
+		if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+			t.Fatalf("begin failed: %v", err)
+		}
+		release = func() {
+			_, _ = conn.ExecContext(ctx, "COMMIT")
+			_ = conn.Close()
+		}
+	}
+
+	lock()
+	if _, err := db.Exec("INSERT INTO items (id) VALUES (1)"); err != nil {
+		t.Fatalf("insert after retries failed: %v", err)
+	}
+	if len(attempts) != 3 || attempts[0] != 1 || attempts[2] != 3 {
+		t.Fatalf("unexpected attempts %v", attempts)
+	}
+
+	attempts = nil
+	lock()
+	unlock := release
+	release = func() {}
+	defer unlock()
+	_, err = db.Exec("INSERT INTO items (id) VALUES (2)")
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrBusy {
+		t.Fatalf("expected busy error once the handler gives up, got %v", err)
+	}
+	if len(attempts) != 5 {
+		t.Fatalf("expected 5 attempts, got %v", attempts)
+	}
+}
+
+func TestOpen_BusyHandlerSkipsMultiStatementExec(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	otherPath := filepath.Join(dir, "other.sqlite")
+	other, err := Open(Config{Path: otherPath})
+	if err != nil {
+		t.Fatalf("open other failed: %v", err)
+	}
+	t.Cleanup(func() { _ = other.Close() })
+	other.MustExec("CREATE TABLE b (id INTEGER PRIMARY KEY)")
+
+	attempts := 0
+	db, err := Open(Config{
+		Path:        filepath.Join(dir, "app.sqlite"),
+		Attachments: map[string]string{"other": otherPath},
+		BusyHandler: func(int) bool {
+			attempts++
+			return attempts < 3
+		},
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	db.MustExec("CREATE TABLE a (id INTEGER PRIMARY KEY AUTOINCREMENT, v INTEGER)")
+
+	conn, err := other.Connx(ctx)
+	if err != nil {
+		t.Fatalf("checkout failed: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatalf("begin failed: %v", err)
+	}
+	defer func() { _, _ = conn.ExecContext(ctx, "ROLLBACK") }()
+
+	_, err = db.Exec("INSERT INTO a (v) VALUES (1); INSERT INTO other.b (id) VALUES (1)")
+	if !IsBusy(err) {
+		t.Fatalf("expected busy error, got %v", err)
+	}
+	if attempts != 0 {
+		t.Fatalf("expected no retries of a multi-statement exec, got %d", attempts)
+	}
+	var rows int
+	if err := db.Get(&rows, "SELECT COUNT(*) FROM a"); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if rows != 1 {
+		t.Fatalf("expected the first statement to run once, got %d rows", rows)
+	}
+}
+
+func TestOpen_BusyHandlerWithBusyTimeout(t *testing.T) {
+	t.Parallel()
+
+	_, err := Open(Config{
+		DSN:         DSN{Path: filepath.Join(t.TempDir(), "app.sqlite"), BusyTimeout: time.Second},
+		BusyHandler: func(int) bool { return false },
+	})
+	if err == nil || !strings.Contains(err.Error(), "busy handler") {
+		t.Fatalf("expected busy timeout conflict, got %v", err)
+	}
+}
@@ -0,0 +1,448 @@
+package sqlite_base
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// CRUDOption configures Insert, Update, GetByID, List and Delete. Each helper
+// ignores the options that do not concern it.
+type CRUDOption func(*crudOptions)
+
+type crudOptions struct {
+	createdColumn string
+	updatedColumn string
+	withTrashed   bool
+	hardDelete    bool
+	where         string
+	whereArgs     []any
+}
+
+func applyCRUDOptions(opts []CRUDOption) crudOptions {
+	var options crudOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// WithTimestamps maintains createdCol on Insert and updatedCol on both Insert
+// and Update; pass "" to skip either. The columns are set to the current time
+// in UTC, or to the created time the caller already set on the row converted
+// to UTC, written as text in go-sqlite3's timestamp layout whether or not the
+// row has a field for the column. A field for either column must be a
+// time.Time, *time.Time or sql.NullTime. The row passed in is not modified.
+func WithTimestamps(createdCol, updatedCol string) CRUDOption {
+	return func(o *crudOptions) {
+		o.createdColumn = createdCol
+		o.updatedColumn = updatedCol
+	}
+}
+
+// WithTrashed makes Update, GetByID and List include soft-deleted rows.
+func WithTrashed() CRUDOption {
+	return func(o *crudOptions) {
+		o.withTrashed = true
+	}
+}
+
+// WithHardDelete makes Delete remove the row even when its type has a
+// soft-delete column.
+func WithHardDelete() CRUDOption {
+	return func(o *crudOptions) {
+		o.hardDelete = true
+	}
+}
+
+// WithWhere restricts List to rows matching condition, a SQL condition using
+// ? placeholders for args.
+func WithWhere(condition string, args ...any) CRUDOption {
+	return func(o *crudOptions) {
+		o.where = condition
+		o.whereArgs = args
+	}
+}
+
+// Insert writes row into table using its `db`-tagged fields and returns the
+// new rowid. Primary key fields (tagged `sqlite:"pk"`) holding their zero
+// value are omitted so SQLite assigns the key.
+func Insert[T any](db *sqlx.DB, table string, row T, opts ...CRUDOption) (int64, error) {
+	columns, err := rowColumns(row)
+	if err != nil {
+		return 0, err
+	}
+	options := applyCRUDOptions(opts)
+
+	now := time.Now().UTC()
+	value := reflect.Indirect(reflect.ValueOf(row))
+	names := make([]string, 0, len(columns)+2)
+	args := make([]any, 0, len(columns)+2)
+	stamped := map[string]bool{}
+	for _, column := range columns {
+		field := value.FieldByIndex(column.index)
+		if column.primaryKey && field.IsZero() {
+			continue
+		}
+		arg := field.Interface()
+		created := strings.EqualFold(column.name, options.createdColumn)
+		if created || strings.EqualFold(column.name, options.updatedColumn) {
+			stamped[strings.ToLower(column.name)] = true
+			if arg, err = timestampArg(column.name, field, now, created); err != nil {
+				return 0, err
+			}
+		}
+		names = append(names, quoteIdent(column.name))
+		args = append(args, arg)
+	}
+	for _, name := range []string{options.createdColumn, options.updatedColumn} {
+		if name == "" || stamped[strings.ToLower(name)] {
+			continue
+		}
+		stamped[strings.ToLower(name)] = true
+		names = append(names, quoteIdent(name))
+		args = append(args, formatTimestamp(now))
+	}
+	params := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(table), strings.Join(names, ", "), params)
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("insert into %s: %w", table, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("read inserted id: %w", err)
+	}
+
+	return id, nil
+}
+
+// Update writes every non-key column of row to the row of table with the
+// same primary key, the field tagged `sqlite:"pk"` or "id" when no field is
+// tagged. The created column of WithTimestamps and the soft-delete column are
+// left untouched, and soft-deleted rows are skipped unless WithTrashed is
+// given. An error wrapping sql.ErrNoRows is returned when no row has that
+// key.
+func Update[T any](db *sqlx.DB, table string, row T, opts ...CRUDOption) error {
+	columns, err := rowColumns(row)
+	if err != nil {
+		return err
+	}
+	options := applyCRUDOptions(opts)
+
+	now := time.Now().UTC()
+	value := reflect.Indirect(reflect.ValueOf(row))
+	var key *structColumn
+	sets := make([]string, 0, len(columns)+1)
+	args := make([]any, 0, len(columns)+1)
+	stamped := false
+	for i, column := range columns {
+		if column.primaryKey || (key == nil && column.name == "id") {
+			key = &columns[i]
+		}
+	}
+	if key == nil {
+		return fmt.Errorf("update %s: row has no primary key field", table)
+	}
+	for _, column := range columns {
+		if column.name == key.name || column.softDelete || strings.EqualFold(column.name, options.createdColumn) {
+			continue
+		}
+		field := value.FieldByIndex(column.index)
+		arg := field.Interface()
+		if strings.EqualFold(column.name, options.updatedColumn) {
+			stamped = true
+			if arg, err = timestampArg(column.name, field, now, false); err != nil {
+				return err
+			}
+		}
+		sets = append(sets, quoteIdent(column.name)+" = ?")
+		args = append(args, arg)
+	}
+	if options.updatedColumn != "" && !stamped {
+		sets = append(sets, quoteIdent(options.updatedColumn)+" = ?")
+		args = append(args, formatTimestamp(now))
+	}
+	if len(sets) == 0 {
+		return fmt.Errorf("update %s: row has no columns to update", table)
+	}
+	args = append(args, value.FieldByIndex(key.index).Interface())
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", quoteIdent(table), strings.Join(sets, ", "), quoteIdent(key.name))
+	if column := softDeleteColumn(columns); column != "" && !options.withTrashed {
+		query += fmt.Sprintf(" AND %s IS NULL", quoteIdent(column))
+	}
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("update %s: %w", table, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("read rows affected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("update %s: %w", table, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// timestampArg returns the value to write for the timestamp field of column
+// name: the time already set in the field when keep is true, otherwise now.
+func timestampArg(name string, field reflect.Value, now time.Time, keep bool) (any, error) {
+	var set time.Time
+	switch v := field.Interface().(type) {
+	case time.Time:
+		set = v
+	case *time.Time:
+		if v != nil {
+			set = *v
+		}
+	case sql.NullTime:
+		if v.Valid {
+			set = v.Time
+		}
+	default:
+		return nil, fmt.Errorf("timestamp column %s must be a time.Time, *time.Time or sql.NullTime field, got %s", name, field.Type())
+	}
+	if keep && !set.IsZero() {
+		now = set
+	}
+
+	return formatTimestamp(now), nil
+}
+
+// formatTimestamp renders t in UTC in the layout go-sqlite3 uses for
+// time.Time arguments, which it parses back for DATETIME and TIMESTAMP
+// columns.
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(sqlite3.SQLiteTimestampFormats[0])
+}
+
+// GetByID loads the row of table whose primary key equals id. The key column
+// is the field tagged `sqlite:"pk"`, or "id" when no field is tagged. A
+// soft-deleted row is not found unless WithTrashed is given.
+func GetByID[T any](db *sqlx.DB, table string, id any, opts ...CRUDOption) (T, error) {
+	var row T
+
+	columns, err := rowColumns(row)
+	if err != nil {
+		return row, err
+	}
+	options := applyCRUDOptions(opts)
+
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, quoteIdent(column.name))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", strings.Join(names, ", "), quoteIdent(table), quoteIdent(keyColumn(columns)))
+	if column := softDeleteColumn(columns); column != "" && !options.withTrashed {
+		query += fmt.Sprintf(" AND %s IS NULL", quoteIdent(column))
+	}
+	if err := db.Get(&row, query, id); err != nil {
+		return row, fmt.Errorf("get %s by id: %w", table, err)
+	}
+
+	return row, nil
+}
+
+// List loads the rows of table ordered by primary key, restricted by
+// WithWhere and, unless WithTrashed is given, to rows that are not
+// soft-deleted.
+func List[T any](db *sqlx.DB, table string, opts ...CRUDOption) ([]T, error) {
+	var zero T
+	columns, err := rowColumns(zero)
+	if err != nil {
+		return nil, err
+	}
+	options := applyCRUDOptions(opts)
+
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, quoteIdent(column.name))
+	}
+
+	var conditions []string
+	if strings.TrimSpace(options.where) != "" {
+		conditions = append(conditions, "("+options.where+")")
+	}
+	if column := softDeleteColumn(columns); column != "" && !options.withTrashed {
+		conditions = append(conditions, quoteIdent(column)+" IS NULL")
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(names, ", "), quoteIdent(table))
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + quoteIdent(keyColumn(columns))
+
+	rows := []T{}
+	if err := db.Select(&rows, query, options.whereArgs...); err != nil {
+		return nil, fmt.Errorf("list %s: %w", table, err)
+	}
+
+	return rows, nil
+}
+
+// Delete removes the row of table whose primary key equals id, the key
+// column being chosen from T as in GetByID. When T has a *time.Time or
+// sql.NullTime field tagged `sqlite:"softdelete"`, NULL for live rows, Delete
+// sets that column to the current time instead, written as WithTimestamps
+// writes it, leaving a row that was already soft-deleted untouched, unless
+// WithHardDelete is given. An error wrapping sql.ErrNoRows is returned when
+// no row was deleted.
+func Delete[T any](db *sqlx.DB, table string, id any, opts ...CRUDOption) error {
+	var zero T
+	columns, err := rowColumns(zero)
+	if err != nil {
+		return err
+	}
+	options := applyCRUDOptions(opts)
+
+	key := quoteIdent(keyColumn(columns))
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", quoteIdent(table), key)
+	args := []any{id}
+	if column := softDeleteColumn(columns); column != "" && !options.hardDelete {
+		query = fmt.Sprintf("UPDATE %[1]s SET %[2]s = ? WHERE %[3]s = ? AND %[2]s IS NULL", quoteIdent(table), quoteIdent(column), key)
+		args = []any{formatTimestamp(time.Now()), id}
+	}
+
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("delete from %s: %w", table, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("read rows affected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("delete from %s: %w", table, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// keyColumn returns the column of the field tagged `sqlite:"pk"`, or "id".
+func keyColumn(columns []structColumn) string {
+	for _, column := range columns {
+		if column.primaryKey {
+			return column.name
+		}
+	}
+
+	return "id"
+}
+
+// softDeleteColumn returns the column of the field tagged
+// `sqlite:"softdelete"`, or "" when rows of the type are deleted outright.
+func softDeleteColumn(columns []structColumn) string {
+	for _, column := range columns {
+		if column.softDelete {
+			return column.name
+		}
+	}
+
+	return ""
+}
+
+// Count returns the number of rows in table matching where, which is a SQL
+// condition using ? placeholders for args. An empty where counts every row.
+func Count(db *sqlx.DB, table, where string, args ...any) (int64, error) {
+	query := "SELECT COUNT(*) FROM " + quoteIdent(table)
+	if strings.TrimSpace(where) != "" {
+		query += " WHERE " + where
+	}
+
+	var n int64
+	if err := db.Get(&n, query, args...); err != nil {
+		return 0, fmt.Errorf("count %s: %w", table, err)
+	}
+
+	return n, nil
+}
+
+// RowExists reports whether any row of table matches where, stopping at the
+// first match.
+func RowExists(db *sqlx.DB, table, where string, args ...any) (bool, error) {
+	query := "SELECT 1 FROM " + quoteIdent(table)
+	if strings.TrimSpace(where) != "" {
+		query += " WHERE " + where
+	}
+
+	var exists bool
+	if err := db.Get(&exists, "SELECT EXISTS ("+query+")", args...); err != nil {
+		return false, fmt.Errorf("check rows in %s: %w", table, err)
+	}
+
+	return exists, nil
+}
+
+// ExecReturningID runs an INSERT and returns the rowid of the inserted row.
+// SQLite reports the connection's most recent insert even when this
+// statement inserted nothing, such as an INSERT OR IGNORE that was ignored,
+// so 0 is returned when no row was affected rather than a stale rowid.
+func ExecReturningID(db *sqlx.DB, query string, args ...any) (int64, error) {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("exec: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("read rows affected: %w", err)
+	}
+	if affected == 0 {
+		return 0, nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("read inserted id: %w", err)
+	}
+
+	return id, nil
+}
+
+// ExecAffected runs a statement and returns the number of rows it changed.
+func ExecAffected(db *sqlx.DB, query string, args ...any) (int64, error) {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("exec: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("read rows affected: %w", err)
+	}
+
+	return affected, nil
+}
+
+func rowColumns(row any) ([]structColumn, error) {
+	t := reflect.TypeOf(row)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.New("row type must be a struct")
+	}
+
+	columns, err := structColumns(t)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("struct %s has no columns", t.Name())
+	}
+
+	return columns, nil
+}
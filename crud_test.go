@@ -0,0 +1,382 @@
+package sqlite_base
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type widget struct {
+	ID    int64  `db:"id" sqlite:"pk"`
+	Name  string `db:"name" sqlite:"notnull"`
+	Price float64
+}
+
+func TestInsertAndGetByID(t *testing.T) {
+	t.Parallel()
+
+	createSQL, _, err := SchemaFromStruct(widget{})
+	if err != nil {
+		t.Fatalf("schema from struct failed: %v", err)
+	}
+	db := newTestDB(t, createSQL)
+
+	id, err := Insert(db, "widget", widget{Name: "sprocket", Price: 2.5})
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	if id != 1 {
+		t.Fatalf("expected id 1, got %d", id)
+	}
+
+	got, err := GetByID[widget](db, "widget", id)
+	if err != nil {
+		t.Fatalf("get by id failed: %v", err)
+	}
+	if want := (widget{ID: 1, Name: "sprocket", Price: 2.5}); got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	if _, err := Insert(db, "widget", &widget{ID: 10, Name: "gear"}); err != nil {
+		t.Fatalf("insert with explicit id failed: %v", err)
+	}
+	if _, err := GetByID[widget](db, "widget", 10); err != nil {
+		t.Fatalf("get explicit id failed: %v", err)
+	}
+
+	_, err = GetByID[widget](db, "widget", 99)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected no rows error, got: %v", err)
+	}
+}
+
+// tagList is stored as a comma-separated string through driver.Valuer and
+// sql.Scanner.
+type tagList []string
+
+func (l tagList) Value() (driver.Value, error) { return strings.Join(l, ","), nil }
+
+func (l *tagList) Scan(src any) error {
+	s, ok := src.(string)
+	if !ok {
+		return fmt.Errorf("scan tag list from %T", src)
+	}
+	*l = strings.Split(s, ",")
+	return nil
+}
+
+type post struct {
+	ID    int64          `db:"id" sqlite:"pk"`
+	Tags  tagList        `db:"tags"`
+	Notes sql.NullString `db:"notes"`
+}
+
+func TestInsertAndGetByID_ValuerAndScannerFields(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE post (id INTEGER PRIMARY KEY, tags TEXT, notes TEXT)")
+
+	id, err := Insert(db, "post", post{Tags: tagList{"go", "sqlite"}, Notes: sql.NullString{String: "draft", Valid: true}})
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	got, err := GetByID[post](db, "post", id)
+	if err != nil {
+		t.Fatalf("get by id failed: %v", err)
+	}
+	if len(got.Tags) != 2 || got.Tags[1] != "sqlite" || got.Notes.String != "draft" {
+		t.Fatalf("unexpected row: %+v", got)
+	}
+}
+
+func TestCountAndRowExists(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, status TEXT NOT NULL)",
+		"INSERT INTO orders (status) VALUES ('open'), ('open'), ('closed')",
+	)
+
+	total, err := Count(db, "orders", "")
+	if err != nil || total != 3 {
+		t.Fatalf("expected 3 orders, got %d (err %v)", total, err)
+	}
+	open, err := Count(db, "orders", "status = ?", "open")
+	if err != nil || open != 2 {
+		t.Fatalf("expected 2 open orders, got %d (err %v)", open, err)
+	}
+
+	exists, err := RowExists(db, "orders", "status = ?", "closed")
+	if err != nil || !exists {
+		t.Fatalf("expected closed order to exist, got %v (err %v)", exists, err)
+	}
+	exists, err = RowExists(db, "orders", "status = ?", "void")
+	if err != nil || exists {
+		t.Fatalf("expected no void order, got %v (err %v)", exists, err)
+	}
+
+	if _, err := Count(db, `orders"; DROP TABLE orders; --`, ""); err == nil {
+		t.Fatal("expected quoted unknown table to fail")
+	}
+}
+
+func TestExecReturningIDAndAffected(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT UNIQUE)")
+
+	id, err := ExecReturningID(db, "INSERT INTO tags (name) VALUES (?)", "go")
+	if err != nil || id != 1 {
+		t.Fatalf("expected id 1, got %d, %v", id, err)
+	}
+	id, err = ExecReturningID(db, "INSERT INTO tags (name) VALUES (?)", "sqlite")
+	if err != nil || id != 2 {
+		t.Fatalf("expected id 2, got %d, %v", id, err)
+	}
+	id, err = ExecReturningID(db, "INSERT OR IGNORE INTO tags (name) VALUES (?)", "go")
+	if err != nil || id != 0 {
+		t.Fatalf("expected 0 for an ignored insert, got %d, %v", id, err)
+	}
+	if _, err := ExecReturningID(db, "INSERT INTO tags (name) VALUES (?)", "go"); err == nil {
+		t.Fatal("expected unique violation")
+	}
+
+	affected, err := ExecAffected(db, "UPDATE tags SET name = upper(name)")
+	if err != nil || affected != 2 {
+		t.Fatalf("expected 2 rows affected, got %d, %v", affected, err)
+	}
+	affected, err = ExecAffected(db, "DELETE FROM tags WHERE name = ?", "missing")
+	if err != nil || affected != 0 {
+		t.Fatalf("expected 0 rows affected, got %d, %v", affected, err)
+	}
+}
+
+type article struct {
+	ID        int64     `db:"id" sqlite:"pk"`
+	Title     string    `db:"title"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func TestInsertAndUpdate_WithTimestamps(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE article (id INTEGER PRIMARY KEY, title TEXT, created_at DATETIME, updated_at DATETIME)")
+	stamps := WithTimestamps("created_at", "updated_at")
+
+	before := time.Now().UTC().Add(-time.Second)
+	id, err := Insert(db, "article", article{Title: "draft"}, stamps)
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	inserted, err := GetByID[article](db, "article", id)
+	if err != nil {
+		t.Fatalf("get by id failed: %v", err)
+	}
+	if inserted.CreatedAt.Before(before) || !inserted.UpdatedAt.Equal(inserted.CreatedAt) {
+		t.Fatalf("expected fresh equal timestamps, got %+v", inserted)
+	}
+	if inserted.CreatedAt.Location() != time.UTC {
+		t.Fatalf("expected timestamps in UTC, got %s", inserted.CreatedAt.Location())
+	}
+
+	imported := time.Date(2020, 1, 2, 8, 4, 5, 0, time.FixedZone("UTC+5", 5*60*60))
+	id, err = Insert(db, "article", article{Title: "imported", CreatedAt: imported}, stamps)
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	got, err := GetByID[article](db, "article", id)
+	if err != nil {
+		t.Fatalf("get by id failed: %v", err)
+	}
+	if !got.CreatedAt.Equal(imported) || got.UpdatedAt.Before(before) {
+		t.Fatalf("expected the caller's created time to be kept, got %+v", got)
+	}
+	var stored string
+	if err := db.Get(&stored, "SELECT created_at || '' FROM article WHERE id = ?", id); err != nil {
+		t.Fatalf("read created time failed: %v", err)
+	}
+	if stored != "2020-01-02 03:04:05+00:00" {
+		t.Fatalf("expected the caller's created time in UTC, got %q", stored)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	inserted.Title = "published"
+	inserted.CreatedAt = time.Time{}
+	if err := Update(db, "article", inserted, stamps); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	updated, err := GetByID[article](db, "article", inserted.ID)
+	if err != nil {
+		t.Fatalf("get by id failed: %v", err)
+	}
+	if updated.Title != "published" || !updated.UpdatedAt.After(updated.CreatedAt) {
+		t.Fatalf("expected a newer updated time, got %+v", updated)
+	}
+	if updated.CreatedAt.IsZero() {
+		t.Fatal("expected update to leave the created time untouched")
+	}
+
+	err = Update(db, "article", article{ID: 99, Title: "missing"}, stamps)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected no rows error, got: %v", err)
+	}
+}
+
+func TestInsertAndUpdate_WithDatabaseTimestamps(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE widget (id INTEGER PRIMARY KEY, name TEXT NOT NULL, price REAL, created_at TEXT, updated_at TEXT)",
+		"CREATE TABLE article (id INTEGER PRIMARY KEY, title TEXT, created_at DATETIME, updated_at DATETIME)",
+	)
+	stamps := WithTimestamps("created_at", "updated_at")
+
+	id, err := Insert(db, "widget", widget{Name: "sprocket"}, stamps)
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	if err := Update(db, "widget", widget{ID: id, Name: "gear"}, WithTimestamps("", "updated_at")); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	var stored struct {
+		CreatedAt string `db:"created_at"`
+		UpdatedAt string `db:"updated_at"`
+	}
+	if err := db.Get(&stored, "SELECT created_at, updated_at FROM widget WHERE id = ?", id); err != nil {
+		t.Fatalf("read timestamps failed: %v", err)
+	}
+	var goStamped string
+	if _, err := Insert(db, "article", article{Title: "go"}, stamps); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	if err := db.Get(&goStamped, "SELECT created_at || '' FROM article"); err != nil {
+		t.Fatalf("read timestamp failed: %v", err)
+	}
+	now := time.Now().UTC()
+	for _, value := range []string{stored.CreatedAt, stored.UpdatedAt, goStamped} {
+		parsed, err := time.Parse("2006-01-02 15:04:05.999999999-07:00", value)
+		if err != nil || !strings.HasSuffix(value, "+00:00") {
+			t.Fatalf("expected a UTC timestamp in go-sqlite3's layout, got %q", value)
+		}
+		if d := now.Sub(parsed); d < -time.Minute || d > time.Minute {
+			t.Fatalf("expected a UTC timestamp near %s, got %s", now, parsed)
+		}
+	}
+
+	type badStamp struct {
+		ID        int64  `db:"id" sqlite:"pk"`
+		CreatedAt string `db:"created_at"`
+	}
+	_, err = Insert(db, "widget", badStamp{}, stamps)
+	if err == nil || !strings.Contains(err.Error(), "must be a time.Time") {
+		t.Fatalf("expected a timestamp field type error, got %v", err)
+	}
+}
+
+type note struct {
+	ID        int64        `db:"id" sqlite:"pk"`
+	Body      string       `db:"body"`
+	DeletedAt sql.NullTime `db:"deleted_at" sqlite:"softdelete"`
+}
+
+func TestInsertAndGetByID_SoftDeleteModel(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE note (id INTEGER PRIMARY KEY, body TEXT, deleted_at DATETIME)")
+
+	id, err := Insert(db, "note", note{Body: "fresh"})
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	got, err := GetByID[note](db, "note", id)
+	if err != nil {
+		t.Fatalf("get by id failed: %v", err)
+	}
+	if got.Body != "fresh" || got.DeletedAt.Valid {
+		t.Fatalf("expected a live row, got %+v", got)
+	}
+
+	type plainTimeNote struct {
+		ID        int64     `db:"id" sqlite:"pk"`
+		DeletedAt time.Time `db:"deleted_at" sqlite:"softdelete"`
+	}
+	_, err = Insert(db, "note", plainTimeNote{})
+	if err == nil || !strings.Contains(err.Error(), "softdelete needs") {
+		t.Fatalf("expected a plain time.Time softdelete field to be rejected, got %v", err)
+	}
+}
+
+func TestDeleteAndList_SoftDelete(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE note (id INTEGER PRIMARY KEY, body TEXT, deleted_at DATETIME)",
+		"INSERT INTO note (body) VALUES ('keep'), ('trash'), ('other')",
+	)
+	if err := Delete[note](db, "note", 2); err != nil {
+		t.Fatalf("soft delete failed: %v", err)
+	}
+	if err := Delete[note](db, "note", 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected no rows error for an already deleted row, got: %v", err)
+	}
+	if n, err := Count(db, "note", ""); err != nil || n != 3 {
+		t.Fatalf("expected soft delete to keep the row, got %d, %v", n, err)
+	}
+
+	if _, err := GetByID[note](db, "note", 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected soft-deleted row to be hidden, got: %v", err)
+	}
+	trashed, err := GetByID[note](db, "note", 2, WithTrashed())
+	if err != nil {
+		t.Fatalf("get with trashed failed: %v", err)
+	}
+	if !trashed.DeletedAt.Valid {
+		t.Fatalf("expected deleted_at to be set, got %+v", trashed)
+	}
+
+	if err := Update(db, "note", note{ID: 2, Body: "edited"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected update to skip the soft-deleted row, got: %v", err)
+	}
+	if err := Update(db, "note", note{ID: 2, Body: "edited"}, WithTrashed()); err != nil {
+		t.Fatalf("update with trashed failed: %v", err)
+	}
+	trashed, err = GetByID[note](db, "note", 2, WithTrashed())
+	if err != nil {
+		t.Fatalf("get with trashed failed: %v", err)
+	}
+	if trashed.Body != "edited" || !trashed.DeletedAt.Valid {
+		t.Fatalf("expected update to keep the row soft-deleted, got %+v", trashed)
+	}
+
+	rows, err := List[note](db, "note")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Body != "keep" || rows[1].Body != "other" {
+		t.Fatalf("expected the live rows, got %+v", rows)
+	}
+	rows, err = List[note](db, "note", WithTrashed(), WithWhere("body <> ?", "keep"))
+	if err != nil {
+		t.Fatalf("list with trashed failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Body != "edited" || rows[1].Body != "other" {
+		t.Fatalf("expected filtered rows including trash, got %+v", rows)
+	}
+
+	if err := Delete[note](db, "note", 1, WithHardDelete()); err != nil {
+		t.Fatalf("hard delete failed: %v", err)
+	}
+	if n, err := Count(db, "note", ""); err != nil || n != 2 {
+		t.Fatalf("expected hard delete to remove the row, got %d, %v", n, err)
+	}
+	rows, err = List[note](db, "note", WithWhere("body = ?", "missing"))
+	if err != nil || len(rows) != 0 {
+		t.Fatalf("expected an empty list, got %+v, %v", rows, err)
+	}
+}
@@ -0,0 +1,156 @@
+package sqlite_base
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ExportCSV runs query and streams the result to w as RFC 4180 CSV: a header
+// row of column names followed by one record per row. NULLs are written as
+// empty fields and BLOBs as their raw bytes.
+func ExportCSV(db *sqlx.DB, query string, args []any, w io.Writer) error {
+	rows, err := db.Queryx(query, args...)
+	if err != nil {
+		return fmt.Errorf("query export rows: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("read export columns: %w", err)
+	}
+
+	out := csv.NewWriter(w)
+	if err := out.Write(columns); err != nil {
+		return fmt.Errorf("write csv header: %w", err)
+	}
+
+	record := make([]string, len(columns))
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return fmt.Errorf("scan export row: %w", err)
+		}
+		for i, value := range values {
+			record[i] = csvField(value)
+		}
+		if err := out.Write(record); err != nil {
+			return fmt.Errorf("write csv record: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate export rows: %w", err)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("flush csv: %w", err)
+	}
+
+	return nil
+}
+
+// ExportTableCSV writes every row of table to w with ExportCSV.
+func ExportTableCSV(db *sqlx.DB, table string, w io.Writer) error {
+	return ExportCSV(db, "SELECT * FROM "+quoteIdent(table), nil, w)
+}
+
+func csvField(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// ImportOptions configures ImportCSV. The zero value reads comma-separated
+// input, stores empty fields as empty strings and fails on the first row
+// that violates a constraint.
+type ImportOptions struct {
+	Delimiter rune
+	// EmptyAsNull stores empty fields as NULL.
+	EmptyAsNull bool
+	// SkipInvalid skips rows that violate NOT NULL, CHECK, UNIQUE or PRIMARY
+	// KEY constraints (INSERT OR IGNORE) instead of failing the import.
+	SkipInvalid bool
+}
+
+// ImportCSV reads CSV from r, using the header row to map fields to the
+// columns of table, and inserts every record in one transaction with the
+// same chunking as BatchInsert. It returns the number of rows inserted,
+// which excludes rows skipped by SkipInvalid.
+func ImportCSV(db *sqlx.DB, table string, r io.Reader, opts ImportOptions) (int, error) {
+	in := csv.NewReader(r)
+	if opts.Delimiter != 0 {
+		in.Comma = opts.Delimiter
+	}
+
+	header, err := in.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, errors.New("csv header is missing")
+		}
+		return 0, fmt.Errorf("read csv header: %w", err)
+	}
+	if err := checkTableColumns(db, table, header); err != nil {
+		return 0, err
+	}
+
+	var rows []map[string]any
+	for {
+		record, err := in.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("read csv record: %w", err)
+		}
+
+		row := make(map[string]any, len(header))
+		for i, column := range header {
+			if opts.EmptyAsNull && record[i] == "" {
+				row[column] = nil
+				continue
+			}
+			row[column] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	columns, err := rowSetColumns(rows)
+	if err != nil {
+		return 0, err
+	}
+	verb := "INSERT"
+	if opts.SkipInvalid {
+		verb = "INSERT OR IGNORE"
+	}
+	inserted, err := batchInsert(db, table, verb, columns, rows, 0, "")
+	if err != nil {
+		return 0, fmt.Errorf("import csv into %s: %w", table, err)
+	}
+
+	return int(inserted), nil
+}
@@ -0,0 +1,75 @@
+package sqlite_base
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExportCSV(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT, score REAL)",
+		`INSERT INTO notes (body, score) VALUES ('plain', 1.5), ('with "quotes", commas', NULL), (NULL, 2)`,
+	)
+
+	var buf bytes.Buffer
+	if err := ExportTableCSV(db, "notes", &buf); err != nil {
+		t.Fatalf("export table failed: %v", err)
+	}
+	want := "id,body,score\n" +
+		"1,plain,1.5\n" +
+		"2,\"with \"\"quotes\"\", commas\",\n" +
+		"3,,2\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := ExportCSV(db, "SELECT body FROM notes WHERE id = ?", []any{1}, &buf); err != nil {
+		t.Fatalf("export query failed: %v", err)
+	}
+	if buf.String() != "body\nplain\n" {
+		t.Fatalf("unexpected query csv:\n%s", buf.String())
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE, nickname TEXT, age INTEGER CHECK (age >= 0))")
+
+	input := "name;nickname;age\nada;;36\ngrace;amazing;85\n"
+	n, err := ImportCSV(db, "people", strings.NewReader(input), ImportOptions{Delimiter: ';', EmptyAsNull: true})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 rows, got %d", n)
+	}
+	var nulls int
+	if err := db.Get(&nulls, "SELECT COUNT(*) FROM people WHERE nickname IS NULL"); err != nil {
+		t.Fatalf("count nulls failed: %v", err)
+	}
+	if nulls != 1 {
+		t.Fatalf("expected empty field stored as NULL, got %d nulls", nulls)
+	}
+
+	invalid := "name,age\nada,1\nlinus,-4\nbarbara,80\n"
+	if _, err := ImportCSV(db, "people", strings.NewReader(invalid), ImportOptions{}); err == nil {
+		t.Fatal("expected constraint violation to fail the import")
+	}
+	n, err = ImportCSV(db, "people", strings.NewReader(invalid), ImportOptions{SkipInvalid: true})
+	if err != nil {
+		t.Fatalf("import with SkipInvalid failed: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected only barbara to be imported, got %d", n)
+	}
+
+	if _, err := ImportCSV(db, "people", strings.NewReader("email\nx\n"), ImportOptions{}); !errors.Is(err, ErrColumnMissing) {
+		t.Fatalf("expected unknown column error, got %v", err)
+	}
+}
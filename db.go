@@ -1,55 +1,413 @@
 package sqlite_base
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io/fs"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 	"github.com/pressly/goose/v3"
 )
 
 type Config struct {
-	Path         string
+	Path string
+	// DSN allows full control over the data source; Path is used when
+	// DSN.Path is empty. The connection-level fields below override the
+	// matching DSN fields when set.
+	DSN          DSN
 	MigrationDir string
 	MigrationFS  fs.FS
+	Schema       Schema
+	Logger       Logger
+
+	// CacheSize is applied as PRAGMA cache_size on every connection: positive
+	// values count pages, negative values count KiB. CacheSizeBytes is the
+	// same setting expressed in bytes; set at most one of the two.
+	CacheSize      int
+	CacheSizeBytes int64
+	// PageSize is applied as PRAGMA page_size before migrations run. It only
+	// takes effect on a new database, so Open fails if a populated database
+	// uses a different page size.
+	PageSize int
+	// Synchronous is applied as PRAGMA synchronous on every connection,
+	// independently of the journal mode. Empty keeps SQLite's default.
+	Synchronous SynchronousMode
+	// ReadOnly opens the database with mode=ro. The file must already exist,
+	// migrations are not applied and the schema is only validated. Immutable
+	// additionally tells SQLite the file cannot change, skipping all locking;
+	// it implies ReadOnly.
+	ReadOnly  bool
+	Immutable bool
+	// Metrics, when set, observes every statement and enables the statement
+	// counters reported by Stats.
+	Metrics MetricsCollector
+	// EncryptionKey is sent as PRAGMA key on every new connection. It
+	// requires a SQLCipher build of the driver; Open fails with
+	// ErrEncryptionUnsupported otherwise.
+	EncryptionKey string
+	// Pragmas are run on every new pooled connection, after the DSN
+	// settings, in name order. Use them for per-connection settings such as
+	// foreign_keys, busy_timeout or temp_store that the DSN does not cover.
+	Pragmas map[string]string
+	// Attachments maps schema aliases to database files that are attached
+	// to every new pooled connection.
+	Attachments map[string]string
+	// ConnectHook runs on every new pooled connection after the settings
+	// above, e.g. to call RegisterFunc or RegisterCollation. Connections are
+	// opened through a private connector rather than a registered driver
+	// name, so any number of databases with different hooks can be opened.
+	ConnectHook func(*sqlite3.SQLiteConn) error
+	// Collations are registered on every new pooled connection, for use as
+	// COLLATE <name> in queries and column definitions. Each function
+	// returns a negative, zero or positive value like strings.Compare.
+	Collations map[string]func(a, b string) int
+	// Functions are registered on every new pooled connection. Open fails
+	// if a function has an unsupported signature.
+	Functions []Function
+	// CrossProcessLock guards initialization with an advisory lock on
+	// Path + ".init.lock" (Unix only), in addition to the in-process lock
+	// Open always takes, so several processes can initialize the same new
+	// database at once.
+	CrossProcessLock bool
+	// DefaultQueryTimeout bounds every statement run through the returned
+	// pool, including prepared statements and the package's own, whose
+	// context has no deadline. The driver interrupts a statement when its
+	// context expires. OpenDB does not apply it, since the connection
+	// belongs to the caller.
+	DefaultQueryTimeout time.Duration
+	// BusyHandler replaces busy_timeout: when a statement fails with
+	// SQLITE_BUSY it is run again for as long as BusyHandler returns true,
+	// with attempt counting from 1. The handler should sleep or back off
+	// before returning true. go-sqlite3 does not expose
+	// sqlite3_busy_handler, so retries happen per statement: a busy COMMIT
+	// or a busy error while reading rows is returned as is. Only queries
+	// holding a single statement are retried; an Exec of several
+	// semicolon-separated statements returns the busy error at once, since
+	// the statements before it have already been applied. It cannot be
+	// combined with DSN.BusyTimeout.
+	BusyHandler func(attempt int) bool
+	// CreateDirs creates the parent directories of the database file with
+	// mode 0755 before connecting. It is off by default so that a mistyped
+	// path fails instead of writing a new directory tree, and it is ignored
+	// for read-only databases.
+	CreateDirs bool
+	// SingleConnection limits the pool to one connection, so every statement
+	// and transaction runs serially on it. SQLite allows one writer at a
+	// time anyway, so for write-heavy single-process workloads this removes
+	// SQLITE_BUSY contention between pooled connections, and per-connection
+	// state such as temp tables and Attach is shared by all callers. The
+	// cost is that reads wait behind writes and each other, and code that
+	// runs a statement while holding open rows or a transaction on the same
+	// *sqlx.DB deadlocks.
+	SingleConnection bool
+	// SharedCache opens every pooled connection with cache=shared, so they
+	// share one page cache, which can save memory and raise hit rates for
+	// read-heavy use within one process. It changes the locking model:
+	// connections lock each other at table level, and a read of a table
+	// another connection is writing fails with SQLITE_LOCKED (see IsLocked)
+	// instead of waiting for busy_timeout. SQLite discourages shared cache
+	// in favour of WAL mode.
+	SharedCache bool
+	// ReadUncommitted sets PRAGMA read_uncommitted on every connection so
+	// that reads skip the table locks of SharedCache and may see
+	// uncommitted changes of other connections. It requires SharedCache,
+	// without which the pragma has no effect.
+	ReadUncommitted bool
+	// MmapSize sets PRAGMA mmap_size, the number of bytes of the database
+	// file read through memory-mapped I/O, which saves read syscalls on large
+	// read-mostly databases. The pragma is per connection, so it is applied
+	// to every pooled connection as it opens. 0 keeps SQLite's default,
+	// which disables mmap. SQLite silently caps the size at the compile-time
+	// SQLITE_MAX_MMAP_SIZE; Open logs a warning through Logger when it does.
+	MmapSize int64
 }
 
-var gooseMu sync.Mutex
+// Function is a Go scalar function callable from SQL, registered with
+// SQLiteConn.RegisterFunc. Pure functions always return the same result for
+// the same arguments, which lets SQLite use them in indexes and constraints.
+type Function struct {
+	Name string
+	Impl any
+	Pure bool
+}
 
+// Open connects to the SQLite database at config.Path, applies pending
+// migrations and validates config.Schema.
+//
+// Statements issued through the context-aware methods (QueryContext,
+// ExecContext, GetContext, ...) are interrupted with sqlite3_interrupt by the
+// driver when their context is cancelled, so a cancelled context stops an
+// in-progress query at its next VM step without any extra configuration.
 func Open(config Config) (*sqlx.DB, error) {
-	if strings.TrimSpace(config.Path) == "" {
+	dsn, err := dataSource(config)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(dsn.Path) == "" {
 		return nil, errors.New("path is required")
 	}
+	readOnly := strings.EqualFold(dsn.Mode, "ro")
+	if config.CreateDirs && !readOnly {
+		if err := createDatabaseDir(dsn.Path); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkDatabasePath(dsn.Path); err != nil {
+		return nil, err
+	}
+
+	if readOnly && !strings.HasPrefix(dsn.Path, "file:") {
+		if _, err := os.Stat(dsn.Path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("read-only database does not exist: %w", err)
+			}
+			return nil, fmt.Errorf("stat database file: %w", err)
+		}
+	}
 
-	db, err := sqlx.Open("sqlite3", config.Path)
+	connector, err := newConnector(dsn.String(), config)
 	if err != nil {
-		return nil, fmt.Errorf("open sqlite database: %w", err)
+		return nil, err
+	}
+	db := sqlx.NewDb(sql.OpenDB(connector), "sqlite3")
+	if config.SingleConnection {
+		db.SetMaxOpenConns(1)
 	}
 
 	if err := db.Ping(); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("ping sqlite database: %w", err)
 	}
+	if err := verifyDatabase(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("verify sqlite database %s: %w", dsn.Path, err)
+	}
 
-	if config.MigrationFS != nil {
-		err = ApplyMigrationsFS(db, config.MigrationFS, config.MigrationDir)
-	} else {
-		err = ApplyMigrations(db, config.MigrationDir)
+	if config.MmapSize > 0 {
+		if err := checkMmapSize(db, config.MmapSize, loggerOrNop(config.Logger)); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
 	}
+
+	unlock, err := lockInit(dsn.Path, config.CrossProcessLock && !readOnly)
 	if err != nil {
 		_ = db.Close()
 		return nil, err
 	}
+	defer unlock()
+
+	if err := initialize(db, config, readOnly); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// checkDatabasePath rejects file paths the driver would accept but not use as
+// expected: a directory, or a file in a directory that does not exist, which
+// only fails later with SQLite's "unable to open database file". :memory:
+// and file: URIs are passed through unchanged.
+func checkDatabasePath(path string) error {
+	if path == ":memory:" || strings.HasPrefix(path, "file:") {
+		return nil
+	}
+
+	dir := filepath.Dir(path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("directory %s of database %s does not exist: %w", dir, path, err)
+		}
+		return fmt.Errorf("stat database directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("parent %s of database %s is not a directory", dir, path)
+	}
+
+	info, err = os.Stat(path)
+	if err == nil && info.IsDir() {
+		return fmt.Errorf("database path %s is a directory", path)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("stat database file: %w", err)
+	}
+
+	return nil
+}
+
+func createDatabaseDir(path string) error {
+	if path == ":memory:" || strings.HasPrefix(path, "file:") {
+		return nil
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create database directory %s: %w", dir, err)
+	}
+
+	return nil
+}
+
+// MustOpen is like Open but panics on error, for simple programs that
+// cannot continue without their database.
+func MustOpen(config Config) *sqlx.DB {
+	db, err := Open(config)
+	if err != nil {
+		panic(err)
+	}
+
+	return db
+}
+
+// OpenDB runs the schema setup of Open on an existing *sql.DB, e.g. one
+// wrapped for tracing or opened with a custom driver: migrations are applied
+// and config.Schema is validated. Only the migration, schema, logger and
+// ReadOnly fields of config are used; the connection is the caller's, and
+// it is not closed when setup fails.
+func OpenDB(sqlDB *sql.DB, config Config) (*sqlx.DB, error) {
+	if sqlDB == nil {
+		return nil, errors.New("database is required")
+	}
+
+	db := sqlx.NewDb(sqlDB, "sqlite3")
+	if err := initialize(db, config, config.ReadOnly || config.Immutable); err != nil {
+		return nil, err
+	}
 
 	return db, nil
 }
 
+// initialize applies migrations, unless the database is read-only, and
+// validates the expected schema.
+func initialize(db *sqlx.DB, config Config, readOnly bool) error {
+	logger := loggerOrNop(config.Logger)
+	if readOnly {
+		logger.Debugf("database opened read-only, skipping migrations")
+	} else if err := prepareSchema(db, config, logger); err != nil {
+		return err
+	}
+
+	if err := ValidateSchema(db, config.Schema); err != nil {
+		return fmt.Errorf("validate schema: %w", err)
+	}
+
+	return nil
+}
+
+// verifyDatabase reads the database header so that a truncated or foreign
+// file is reported by Open rather than by the first query. go-sqlite3
+// already reads it while connecting, so this keeps the guarantee for
+// drivers and connection setups that do not.
+func verifyDatabase(db *sqlx.DB) error {
+	var version int
+	return db.Get(&version, "PRAGMA schema_version")
+}
+
+const maxWaitBackoff = 30 * time.Second
+
+// WaitForDB calls Open until it succeeds or ctx is done, for environments
+// where the database directory may appear after the process starts. Only
+// transient failures are retried: a missing directory or file, SQLITE_BUSY,
+// SQLITE_LOCKED and SQLITE_CANTOPEN. Any other error, such as an invalid
+// Config, a failed migration or a schema validation error, is returned at
+// once. The delay between attempts starts at retry and doubles up to 30
+// seconds. When ctx ends first, the returned error wraps both ctx.Err() and
+// the last Open error.
+func WaitForDB(ctx context.Context, config Config, retry time.Duration) (*sqlx.DB, error) {
+	if retry <= 0 {
+		return nil, errors.New("retry interval must be positive")
+	}
+
+	delay := retry
+	for {
+		db, err := Open(config)
+		if err == nil {
+			return db, nil
+		}
+		if !isTransientOpenError(err) {
+			return nil, err
+		}
+		loggerOrNop(config.Logger).Warnf("open sqlite database failed, retrying in %s: %v", delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("wait for database: %w: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, max(maxWaitBackoff, retry))
+	}
+}
+
+func isTransientOpenError(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || IsBusy(err) || IsLocked(err) || hasErrorCode(err, sqlite3.ErrCantOpen)
+}
+
+// CloseDB folds the WAL back into the main database file with
+// PRAGMA wal_checkpoint(TRUNCATE) and then closes the pool, so a clean
+// shutdown does not leave a large -wal file behind. The checkpoint is skipped
+// when the database is not in WAL mode. The pool is closed even if the
+// checkpoint fails; both errors are returned.
+func CloseDB(db *sqlx.DB) error {
+	checkpointErr := truncateWAL(db)
+	if err := db.Close(); err != nil {
+		return errors.Join(checkpointErr, fmt.Errorf("close sqlite database: %w", err))
+	}
+
+	return checkpointErr
+}
+
+func truncateWAL(db *sqlx.DB) error {
+	var mode string
+	if err := db.Get(&mode, "PRAGMA journal_mode"); err != nil {
+		return fmt.Errorf("read journal mode: %w", err)
+	}
+	if !strings.EqualFold(mode, "wal") {
+		return nil
+	}
+
+	busy, _, _, err := Checkpoint(db, CheckpointTruncate)
+	if err != nil {
+		return err
+	}
+	if busy != 0 {
+		return errors.New("checkpoint wal: database is busy")
+	}
+
+	return nil
+}
+
+func prepareSchema(db *sqlx.DB, config Config, logger Logger) error {
+	if err := applyPageSize(db, config.PageSize); err != nil {
+		return err
+	}
+
+	if config.MigrationFS != nil {
+		return applyMigrationsFS(db, config.MigrationFS, config.MigrationDir, logger)
+	}
+
+	return applyMigrations(db, config.MigrationDir, logger)
+}
+
+// ApplyMigrations applies the pending goose migrations in migrationDir.
+// goose reports its progress through the standard library's log package.
 func ApplyMigrations(db *sqlx.DB, migrationDir string) error {
+	return applyMigrations(db, migrationDir, nil)
+}
+
+func applyMigrations(db *sqlx.DB, migrationDir string, logger Logger) error {
 	if strings.TrimSpace(migrationDir) == "" {
 		return nil
 	}
@@ -81,17 +439,24 @@ func ApplyMigrations(db *sqlx.DB, migrationDir string) error {
 		}
 	}
 	if !hasSQL {
+		loggerOrNop(logger).Debugf("no migrations found in %s", migrationDir)
 		return nil
 	}
 
-	if err := runGooseUp(db, nil, migrationDir); err != nil {
+	if err := runGooseUp(db, nil, migrationDir, logger); err != nil {
 		return fmt.Errorf("apply migrations: %w", err)
 	}
 
 	return nil
 }
 
+// ApplyMigrationsFS is ApplyMigrations for migrations in migrationDir of
+// migrationFS, such as an embed.FS.
 func ApplyMigrationsFS(db *sqlx.DB, migrationFS fs.FS, migrationDir string) error {
+	return applyMigrationsFS(db, migrationFS, migrationDir, nil)
+}
+
+func applyMigrationsFS(db *sqlx.DB, migrationFS fs.FS, migrationDir string, logger Logger) error {
 	if migrationFS == nil || strings.TrimSpace(migrationDir) == "" {
 		return nil
 	}
@@ -115,26 +480,68 @@ func ApplyMigrationsFS(db *sqlx.DB, migrationFS fs.FS, migrationDir string) erro
 		}
 	}
 	if !hasSQL {
+		loggerOrNop(logger).Debugf("no migrations found in %s", migrationDir)
 		return nil
 	}
 
-	if err := runGooseUp(db, migrationFS, migrationDir); err != nil {
+	if err := runGooseUp(db, migrationFS, migrationDir, logger); err != nil {
 		return fmt.Errorf("apply migrations: %w", err)
 	}
 
 	return nil
 }
 
-func runGooseUp(db *sqlx.DB, migrationFS fs.FS, migrationDir string) error {
-	gooseMu.Lock()
-	defer gooseMu.Unlock()
+// runGooseUp applies the pending migrations through a goose Provider, which
+// keeps the dialect, file system and logger per call instead of in goose's
+// globals. Progress is reported in the format of goose.Up, through logger
+// at debug level or, when logger is nil, through the standard log package
+// as goose.Up does.
+func runGooseUp(db *sqlx.DB, migrationFS fs.FS, migrationDir string, logger Logger) error {
+	var fsys fs.FS
+	if migrationFS == nil {
+		fsys = os.DirFS(migrationDir)
+	} else {
+		sub, err := fs.Sub(migrationFS, migrationDir)
+		if err != nil {
+			return fmt.Errorf("open migration dir: %w", err)
+		}
+		fsys = sub
+	}
+	printf := log.Printf
+	if logger != nil {
+		printf = logger.Debugf
+	}
 
-	goose.SetBaseFS(migrationFS)
-	defer goose.SetBaseFS(nil)
+	ctx := context.Background()
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db.DB, fsys)
+	if err != nil {
+		return fmt.Errorf("create goose provider: %w", err)
+	}
+	results, err := provider.Up(ctx)
+	var partial *goose.PartialError
+	if errors.As(err, &partial) {
+		results = partial.Applied
+	}
+	for _, result := range results {
+		format := "OK   %s (%s)"
+		if result.Empty {
+			format = "EMPTY %s (%s)"
+		}
+		printf(format, filepath.Base(result.Source.Path), result.Duration.Round(time.Microsecond))
+	}
+	if err != nil {
+		return err
+	}
 
-	if err := goose.SetDialect("sqlite3"); err != nil {
-		return fmt.Errorf("set goose dialect: %w", err)
+	if len(results) == 0 {
+		current, err := provider.GetDBVersion(ctx)
+		if err != nil {
+			return fmt.Errorf("read migration version: %w", err)
+		}
+		printf("goose: no migrations to run. current version: %d", current)
+		return nil
 	}
+	printf("goose: successfully migrated database to version: %d", results[len(results)-1].Source.Version)
 
-	return goose.Up(db.DB, migrationDir)
+	return nil
 }
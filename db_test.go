@@ -1,10 +1,15 @@
 package sqlite_base
 
 import (
+	"context"
+	"database/sql"
 	"embed"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -26,8 +31,51 @@ func TestOpen_RejectsMissingParentDir(t *testing.T) {
 
 	path := filepath.Join(t.TempDir(), "missing", "db.sqlite")
 	_, err := Open(Config{Path: path})
-	if err == nil {
-		t.Fatal("expected error for missing parent dir")
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected error for missing parent dir, got: %v", err)
+	}
+}
+
+func TestOpen_CreateDirs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "var", "lib", "app", "db.sqlite")
+	db, err := Open(Config{Path: path, CreateDirs: true})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	_ = db.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected database file to be created: %v", err)
+	}
+
+	file := filepath.Join(dir, "plain.txt")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	_, err = Open(Config{Path: filepath.Join(file, "sub", "db.sqlite"), CreateDirs: true})
+	if err == nil || !strings.Contains(err.Error(), "create database directory") {
+		t.Fatalf("expected directory creation error, got: %v", err)
+	}
+}
+
+func TestOpen_RejectsDirectoryPath(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	_, err := Open(Config{Path: dir})
+	if err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Fatalf("expected directory error, got: %v", err)
+	}
+
+	file := filepath.Join(dir, "plain.txt")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+	_, err = Open(Config{Path: filepath.Join(file, "app.sqlite")})
+	if err == nil || !strings.Contains(err.Error(), "is not a directory") {
+		t.Fatalf("expected parent error, got: %v", err)
 	}
 }
 
@@ -159,3 +207,222 @@ func TestApplyMigrationsFS_EmptyOrMissingNoOp(t *testing.T) {
 		t.Fatalf("missing migration dir should be noop: %v", err)
 	}
 }
+
+func newTestDB(t *testing.T, stmts ...string) *sqlx.DB {
+	t.Helper()
+
+	db := sqlx.MustOpen("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("exec %q failed: %v", stmt, err)
+		}
+	}
+
+	return db
+}
+
+func TestOpen_ReadOnly(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "app.sqlite")
+	_, err := Open(Config{Path: dbPath, ReadOnly: true})
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected missing file error, got: %v", err)
+	}
+
+	db, err := Open(Config{Path: dbPath})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	db.MustExec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	db.MustExec("INSERT INTO users (name) VALUES ('alice')")
+	_ = db.Close()
+
+	for _, config := range []Config{{Path: dbPath, ReadOnly: true}, {Path: dbPath, Immutable: true}} {
+		config.Schema = Schema{Columns: map[string]map[string]string{"users": {"name": "TEXT"}}}
+		db, err := Open(config)
+		if err != nil {
+			t.Fatalf("open read-only failed: %v", err)
+		}
+
+		var name string
+		if err := db.Get(&name, "SELECT name FROM users"); err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if _, err := db.Exec("INSERT INTO users (name) VALUES ('bob')"); err == nil {
+			t.Fatal("expected write to read-only database to fail")
+		}
+		_ = db.Close()
+	}
+}
+
+func TestWaitForDB_RetriesUntilDirectoryExists(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "mounted")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = os.Mkdir(dir, 0o755)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	db, err := WaitForDB(ctx, Config{Path: filepath.Join(dir, "app.sqlite")}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("wait for db failed: %v", err)
+	}
+	_ = db.Close()
+}
+
+func TestWaitForDB_ReturnsLastErrorOnDeadline(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := WaitForDB(ctx, Config{Path: filepath.Join(t.TempDir(), "missing", "app.sqlite")}, 10*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected deadline error wrapping the open error, got: %v", err)
+	}
+}
+
+func TestWaitForDB_FailsFastOnPermanentErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	configs := []Config{
+		{Path: filepath.Join(dir, "a.sqlite"), Synchronous: "SOMETIMES"},
+		{Path: filepath.Join(dir, "b.sqlite"), Schema: Schema{Columns: map[string]map[string]string{"users": {"name": "TEXT"}}}},
+		{Path: dir},
+	}
+	for _, config := range configs {
+		start := time.Now()
+		_, err := WaitForDB(ctx, config, 10*time.Millisecond)
+		if err == nil || errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the open error, got: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("expected %v to fail without retrying, took %s", err, elapsed)
+		}
+	}
+}
+
+func TestCloseDB_TruncatesWAL(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "app.sqlite")
+	db, err := Open(Config{DSN: DSN{Path: path, JournalMode: "WAL"}})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	db.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY, body TEXT)")
+	for i := 0; i < 50; i++ {
+		db.MustExec("INSERT INTO items (body) VALUES (?)", strings.Repeat("x", 1024))
+	}
+
+	if err := CloseDB(db); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if info, err := os.Stat(path + "-wal"); err == nil && info.Size() != 0 {
+		t.Fatalf("expected empty or removed wal file, got %d bytes", info.Size())
+	}
+}
+
+func TestCloseDB_WithoutWAL(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite")})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	if err := CloseDB(db); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if err := db.Ping(); err == nil {
+		t.Fatal("expected closed database to fail ping")
+	}
+}
+
+func TestOpen_RejectsNonDatabaseFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "garbage.sqlite")
+	if err := os.WriteFile(path, []byte(strings.Repeat("not a database ", 100)), 0o600); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	_, err := Open(Config{Path: path})
+	if err == nil || !strings.Contains(err.Error(), "file is not a database") {
+		t.Fatalf("expected not a database error, got %v", err)
+	}
+}
+
+func TestOpenDB_UsesExistingConnection(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "app.sqlite"))
+	if err != nil {
+		t.Fatalf("sql open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	db, err := OpenDB(sqlDB, Config{
+		MigrationDir: "examples/migrations",
+		MigrationFS:  embedMigrations,
+		Schema:       Schema{Columns: map[string]map[string]string{"users": {"email": "TEXT"}}},
+	})
+	if err != nil {
+		t.Fatalf("open db failed: %v", err)
+	}
+	if db.DB != sqlDB {
+		t.Fatal("expected OpenDB to wrap the given connection")
+	}
+
+	_, err = OpenDB(sqlDB, Config{Schema: Schema{Columns: map[string]map[string]string{"missing": {"id": "INTEGER"}}}})
+	if err == nil || !strings.Contains(err.Error(), "table missing is missing") {
+		t.Fatalf("expected schema validation error, got %v", err)
+	}
+	if err := sqlDB.Ping(); err != nil {
+		t.Fatalf("expected caller's connection to stay open: %v", err)
+	}
+}
+
+func TestMustOpen(t *testing.T) {
+	t.Parallel()
+
+	db := MustOpen(Config{Path: filepath.Join(t.TempDir(), "app.sqlite")})
+	_ = db.Close()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected MustOpen to panic without a path")
+		}
+	}()
+	MustOpen(Config{})
+}
+
+func TestOpen_SingleConnection(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite"), SingleConnection: true})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if max := db.Stats().MaxOpenConnections; max != 1 {
+		t.Fatalf("expected one connection, got %d", max)
+	}
+
+	db.MustExec("CREATE TEMP TABLE scratch (id INTEGER)")
+	db.MustExec("INSERT INTO scratch (id) VALUES (1)")
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM scratch"); err != nil {
+		t.Fatalf("expected temp table on the shared connection: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package sqlite_base
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TableDefinition describes a table once for both creating and validating
+// it: CreateSQL renders its DDL, and listing it in Schema.Tables checks the
+// live table against the same columns.
+type TableDefinition struct {
+	Name    string
+	Columns []ColumnSpec
+}
+
+// CreateSQL renders the CREATE TABLE statement for the definition. A single
+// PrimaryKey column is declared inline; several are combined into a table
+// PRIMARY KEY constraint.
+func (d TableDefinition) CreateSQL() (string, error) {
+	return d.createSQL("CREATE TABLE")
+}
+
+func (d TableDefinition) createSQL(verb string) (string, error) {
+	if strings.TrimSpace(d.Name) == "" {
+		return "", errors.New("table name is required")
+	}
+	if len(d.Columns) == 0 {
+		return "", fmt.Errorf("table %s has no columns", d.Name)
+	}
+
+	var pk []string
+	for _, column := range d.Columns {
+		if strings.TrimSpace(column.Name) == "" {
+			return "", fmt.Errorf("table %s: column name is required", d.Name)
+		}
+		if column.PrimaryKey {
+			pk = append(pk, quoteIdent(column.Name))
+		}
+	}
+
+	defs := make([]string, 0, len(d.Columns)+1)
+	for _, column := range d.Columns {
+		if len(pk) > 1 {
+			column.PrimaryKey = false
+		}
+		defs = append(defs, column.definition())
+	}
+	if len(pk) > 1 {
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(pk, ", ")+")")
+	}
+
+	return fmt.Sprintf("%s %s (%s)", verb, quoteIdent(d.Name), strings.Join(defs, ", ")), nil
+}
+
+// validateTableDefinition checks every column of expected: its type with
+// match, NOT NULL and primary key membership exactly, the default when one
+// is given, and for Unique columns that a single-column unique index covers
+// it. Columns missing from the definition are ignored.
+func validateTableDefinition(db *sqlx.DB, expected TableDefinition, match TypeComparator) error {
+	if match == nil {
+		match = CaseInsensitiveMatch
+	}
+
+	exists, err := tableExists(db, expected.Name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &ValidationError{Err: ErrTableMissing, Table: expected.Name}
+	}
+
+	columns, err := GetTableColumns(db, expected.Name)
+	if err != nil {
+		return err
+	}
+	actual := make(map[string]ColumnInfo, len(columns))
+	for _, column := range columns {
+		actual[strings.ToLower(column.Name)] = column
+	}
+
+	for _, spec := range expected.Columns {
+		column, ok := actual[strings.ToLower(spec.Name)]
+		if !ok {
+			return &ValidationError{Err: ErrColumnMissing, Table: expected.Name, Column: spec.Name}
+		}
+		if !match(spec.Type, column.Type) {
+			return &ValidationError{Err: ErrColumnTypeMismatch, Table: expected.Name, Column: spec.Name, Expected: spec.Type, Actual: column.Type}
+		}
+		if spec.NotNull != column.NotNull {
+			return fmt.Errorf("table %s: column %s expected not null=%t, got not null=%t", expected.Name, spec.Name, spec.NotNull, column.NotNull)
+		}
+		if spec.PrimaryKey != (column.PrimaryKey > 0) {
+			return fmt.Errorf("table %s: column %s expected primary key=%t, got primary key=%t", expected.Name, spec.Name, spec.PrimaryKey, column.PrimaryKey > 0)
+		}
+		if spec.Default != "" && !strings.EqualFold(strings.TrimSpace(spec.Default), strings.TrimSpace(column.DefaultValue.String)) {
+			return fmt.Errorf("table %s: column %s expected default %s, got %q", expected.Name, spec.Name, spec.Default, column.DefaultValue.String)
+		}
+		if spec.Unique && !spec.PrimaryKey {
+			var count int
+			err := db.Get(&count, `SELECT COUNT(1) FROM pragma_index_list(?) AS l
+				WHERE l."unique" = 1
+				AND (SELECT COUNT(1) FROM pragma_index_info(l.name)) = 1
+				AND (SELECT name FROM pragma_index_info(l.name)) = ? COLLATE NOCASE`, expected.Name, column.Name)
+			if err != nil {
+				return fmt.Errorf("query index list for %s: %w", expected.Name, err)
+			}
+			if count == 0 {
+				return fmt.Errorf("table %s: column %s is not UNIQUE", expected.Name, spec.Name)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package sqlite_base
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTableDefinition_CreateSQL(t *testing.T) {
+	t.Parallel()
+
+	users := TableDefinition{Name: "users", Columns: []ColumnSpec{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "email", Type: "TEXT", NotNull: true, Unique: true},
+		{Name: "status", Type: "TEXT", NotNull: true, Default: "'active'"},
+	}}
+	got, err := users.CreateSQL()
+	if err != nil {
+		t.Fatalf("create sql failed: %v", err)
+	}
+	want := `CREATE TABLE "users" ("id" INTEGER PRIMARY KEY, "email" TEXT NOT NULL UNIQUE, "status" TEXT NOT NULL DEFAULT 'active')`
+	if got != want {
+		t.Fatalf("unexpected sql:\n%s", got)
+	}
+
+	members := TableDefinition{Name: "members", Columns: []ColumnSpec{
+		{Name: "group_id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "user_id", Type: "INTEGER", PrimaryKey: true},
+	}}
+	got, err = members.CreateSQL()
+	if err != nil {
+		t.Fatalf("create sql failed: %v", err)
+	}
+	if !strings.HasSuffix(got, `"user_id" INTEGER, PRIMARY KEY ("group_id", "user_id"))`) {
+		t.Fatalf("expected table primary key constraint, got:\n%s", got)
+	}
+
+	if _, err := (TableDefinition{Name: "empty"}).CreateSQL(); err == nil {
+		t.Fatal("expected table without columns to fail")
+	}
+}
+
+func TestValidateSchema_Tables(t *testing.T) {
+	t.Parallel()
+
+	users := TableDefinition{Name: "users", Columns: []ColumnSpec{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "email", Type: "TEXT", NotNull: true, Unique: true},
+		{Name: "status", Type: "TEXT", NotNull: true, Default: "'active'"},
+	}}
+	createSQL, err := users.CreateSQL()
+	if err != nil {
+		t.Fatalf("create sql failed: %v", err)
+	}
+	db := newTestDB(t, createSQL, "CREATE TABLE loose (id INTEGER, email TEXT, status TEXT DEFAULT 'new')")
+
+	if err := ValidateSchema(db, Schema{Tables: []TableDefinition{users}}); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+
+	loose := users
+	loose.Name = "loose"
+	err = ValidateSchema(db, Schema{Tables: []TableDefinition{loose}})
+	if err == nil || !strings.Contains(err.Error(), "primary key") {
+		t.Fatalf("expected primary key mismatch, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		column ColumnSpec
+		want   string
+	}{
+		{"not null", ColumnSpec{Name: "email", Type: "TEXT", NotNull: true}, "not null"},
+		{"default", ColumnSpec{Name: "status", Type: "TEXT", Default: "'active'"}, "default"},
+		{"unique", ColumnSpec{Name: "email", Type: "TEXT", Unique: true}, "UNIQUE"},
+	}
+	for _, tt := range tests {
+		table := TableDefinition{Name: "loose", Columns: []ColumnSpec{tt.column}}
+		err := ValidateSchema(db, Schema{Tables: []TableDefinition{table}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected %q mismatch, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	err = ValidateSchema(db, Schema{Tables: []TableDefinition{{Name: "users", Columns: []ColumnSpec{{Name: "email", Type: "BLOB", NotNull: true}}}}})
+	if !errors.Is(err, ErrColumnTypeMismatch) {
+		t.Fatalf("expected type mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,464 @@
+package sqlite_base
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type schemaObject struct {
+	Name  string `db:"name"`
+	Table string `db:"tbl_name"`
+	SQL   string `db:"sql"`
+}
+
+func schemaObjects(db *sqlx.DB, objectType string) (map[string]schemaObject, []string, error) {
+	var objects []schemaObject
+	err := db.Select(&objects, `SELECT name, tbl_name, sql FROM sqlite_master WHERE type = ? AND sql IS NOT NULL AND name NOT LIKE 'sqlite\_%' ESCAPE '\' ORDER BY name`, objectType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list %ss: %w", objectType, err)
+	}
+
+	byName := make(map[string]schemaObject, len(objects))
+	names := make([]string, 0, len(objects))
+	for _, object := range objects {
+		byName[strings.ToLower(object.Name)] = object
+		names = append(names, object.Name)
+	}
+
+	return byName, names, nil
+}
+
+// GenerateMigration diffs the schemas of from and to and returns the SQL that
+// transforms from into to. Added tables, columns and indexes map to CREATE
+// TABLE, ALTER TABLE ADD COLUMN and CREATE INDEX; dropped tables and indexes
+// to DROP statements. ADD COLUMN reuses the column definition of to's CREATE
+// TABLE and is only used when nothing else in the table changed. Changes
+// SQLite's ALTER TABLE cannot express, such as dropped or retyped columns or
+// changed table constraints, are emitted as table rebuild blocks that copy
+// the shared columns into a new table with foreign keys off and re-create
+// the table's triggers; since PRAGMA foreign_keys is ignored inside a
+// transaction, such a script must run outside one.
+func GenerateMigration(from, to *sqlx.DB) (string, error) {
+	fromTables, fromTableNames, err := schemaObjects(from, "table")
+	if err != nil {
+		return "", err
+	}
+	toTables, toTableNames, err := schemaObjects(to, "table")
+	if err != nil {
+		return "", err
+	}
+	fromIndexes, fromIndexNames, err := schemaObjects(from, "index")
+	if err != nil {
+		return "", err
+	}
+	toIndexes, toIndexNames, err := schemaObjects(to, "index")
+	if err != nil {
+		return "", err
+	}
+
+	var stmts []string
+	rebuilt := make(map[string]bool)
+
+	for _, name := range fromIndexNames {
+		index := fromIndexes[strings.ToLower(name)]
+		target, ok := toIndexes[strings.ToLower(name)]
+		if !ok || normalizeSQL(target.SQL) != normalizeSQL(index.SQL) {
+			stmts = append(stmts, "DROP INDEX IF EXISTS "+quoteIdent(name)+";")
+		}
+	}
+
+	for _, name := range fromTableNames {
+		if _, ok := toTables[strings.ToLower(name)]; !ok {
+			stmts = append(stmts, "DROP TABLE "+quoteIdent(name)+";")
+		}
+	}
+
+	for _, name := range toTableNames {
+		target := toTables[strings.ToLower(name)]
+		current, ok := fromTables[strings.ToLower(name)]
+		if !ok {
+			stmts = append(stmts, target.SQL+";")
+			continue
+		}
+		if canonicalSQL(current.SQL) == canonicalSQL(target.SQL) {
+			continue
+		}
+
+		tableStmts, rebuild, err := diffTable(from, to, current, target)
+		if err != nil {
+			return "", err
+		}
+		if rebuild {
+			rebuilt[strings.ToLower(name)] = true
+		}
+		stmts = append(stmts, tableStmts...)
+	}
+
+	for _, name := range toIndexNames {
+		index := toIndexes[strings.ToLower(name)]
+		current, ok := fromIndexes[strings.ToLower(name)]
+		if ok && normalizeSQL(current.SQL) == normalizeSQL(index.SQL) && !rebuilt[strings.ToLower(index.Table)] {
+			continue
+		}
+		stmts = append(stmts, index.SQL+";")
+	}
+
+	if len(stmts) == 0 {
+		return "", nil
+	}
+
+	return strings.Join(stmts, "\n") + "\n", nil
+}
+
+func diffTable(from, to *sqlx.DB, currentTable, target schemaObject) ([]string, bool, error) {
+	table := currentTable.Name
+	currentColumns, err := GetTableColumns(from, table)
+	if err != nil {
+		return nil, false, err
+	}
+	targetColumns, err := GetTableColumns(to, target.Name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	current := make(map[string]ColumnInfo, len(currentColumns))
+	for _, column := range currentColumns {
+		current[strings.ToLower(column.Name)] = column
+	}
+	targetNames := make(map[string]bool, len(targetColumns))
+
+	var added []ColumnInfo
+	rebuild := false
+	for _, column := range targetColumns {
+		targetNames[strings.ToLower(column.Name)] = true
+		existing, ok := current[strings.ToLower(column.Name)]
+		if !ok {
+			if !canAddColumn(column) {
+				rebuild = true
+			}
+			added = append(added, column)
+			continue
+		}
+		// ADD COLUMN appends, so an existing column after a new one means
+		// the column order changed.
+		if len(added) > 0 {
+			rebuild = true
+		}
+		if !strings.EqualFold(existing.Type, column.Type) || existing.NotNull != column.NotNull ||
+			existing.PrimaryKey != column.PrimaryKey || existing.DefaultValue != column.DefaultValue ||
+			existing.Hidden != column.Hidden {
+			rebuild = true
+		}
+	}
+	for _, column := range currentColumns {
+		if !targetNames[strings.ToLower(column.Name)] {
+			rebuild = true
+		}
+	}
+
+	if !rebuild && len(added) > 0 {
+		if stmts, ok := addColumnStatements(table, currentTable.SQL, target.SQL, added); ok {
+			return stmts, false, nil
+		}
+	}
+
+	// Only constraints, table options or clauses of the added columns that
+	// ADD COLUMN cannot express differ, or the columns changed: rebuild.
+	var triggers []string
+	err = from.Select(&triggers, `SELECT sql FROM sqlite_master WHERE type = 'trigger' AND tbl_name = ? COLLATE NOCASE AND sql IS NOT NULL ORDER BY name`, table)
+	if err != nil {
+		return nil, false, fmt.Errorf("read triggers of %s: %w", table, err)
+	}
+
+	tmp := target.Name + "_new"
+	createSQL, err := renameCreateTable(target.SQL, tmp)
+	if err != nil {
+		return nil, false, err
+	}
+	var shared []string
+	for _, column := range targetColumns {
+		if existing, ok := current[strings.ToLower(column.Name)]; ok && column.Generated() == "" && existing.Generated() == "" {
+			shared = append(shared, quoteIdent(column.Name))
+		}
+	}
+	list := strings.Join(shared, ", ")
+
+	// As in rebuildTable, foreign keys are off so that dropping the old
+	// table neither cascades to nor fails on child rows, and
+	// legacy_alter_table is on so that the rename leaves views and triggers
+	// that refer to the table untouched. PRAGMA foreign_keys has no effect
+	// inside a transaction, so the script must run outside one.
+	stmts := []string{
+		fmt.Sprintf("-- rebuild %s: SQLite cannot alter it in place", table),
+		"PRAGMA foreign_keys = OFF;",
+		"PRAGMA legacy_alter_table = ON;",
+		createSQL + ";",
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s;", quoteIdent(tmp), list, list, quoteIdent(table)),
+		"DROP TABLE " + quoteIdent(table) + ";",
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", quoteIdent(tmp), quoteIdent(target.Name)),
+	}
+	for _, trigger := range triggers {
+		stmts = append(stmts, trigger+";")
+	}
+	stmts = append(stmts,
+		"PRAGMA legacy_alter_table = OFF;",
+		"-- PRAGMA foreign_key_check must return no rows before foreign keys are enabled again",
+		"PRAGMA foreign_key_check;",
+		"PRAGMA foreign_keys = ON;",
+	)
+
+	return stmts, true, nil
+}
+
+// addColumnStatements returns ALTER TABLE ADD COLUMN statements for added,
+// using each column's definition from targetSQL verbatim so that REFERENCES,
+// CHECK and COLLATE clauses are kept. It reports false when the target
+// differs from the current table in anything but those columns, or when a
+// definition carries a constraint ADD COLUMN rejects.
+func addColumnStatements(table, currentSQL, targetSQL string, added []ColumnInfo) ([]string, bool) {
+	currentDefs, currentOptions, ok := tableDefinitions(currentSQL)
+	if !ok {
+		return nil, false
+	}
+	targetDefs, targetOptions, ok := tableDefinitions(targetSQL)
+	if !ok || canonicalSQL(currentOptions) != canonicalSQL(targetOptions) {
+		return nil, false
+	}
+
+	addedDefs := make(map[string]string, len(added))
+	for _, column := range added {
+		addedDefs[strings.ToLower(column.Name)] = ""
+	}
+	var remaining []string
+	for _, def := range targetDefs {
+		name, isColumn := definitionColumn(def)
+		if _, ok := addedDefs[strings.ToLower(name)]; isColumn && ok {
+			addedDefs[strings.ToLower(name)] = def
+			continue
+		}
+		remaining = append(remaining, def)
+	}
+	if len(remaining) != len(currentDefs) {
+		return nil, false
+	}
+	for i := range remaining {
+		if canonicalSQL(remaining[i]) != canonicalSQL(currentDefs[i]) {
+			return nil, false
+		}
+	}
+
+	stmts := make([]string, 0, len(added))
+	for _, column := range added {
+		def := addedDefs[strings.ToLower(column.Name)]
+		if def == "" {
+			return nil, false
+		}
+		for _, token := range sqlTokens(def) {
+			if token.word && !token.literal && strings.EqualFold(token.text, "UNIQUE") {
+				return nil, false
+			}
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", quoteIdent(table), def))
+	}
+
+	return stmts, true
+}
+
+// tableDefinitions splits the parenthesized body of a CREATE TABLE statement
+// into its column definitions and table constraints, and returns the table
+// options that follow it.
+func tableDefinitions(createSQL string) (defs []string, options string, ok bool) {
+	depth := 0
+	start := -1
+	for _, token := range sqlTokens(createSQL) {
+		if token.word {
+			continue
+		}
+		switch token.text {
+		case "(":
+			depth++
+			if depth == 1 {
+				start = token.end
+			}
+		case ")":
+			depth--
+			if depth == 0 && start >= 0 {
+				defs = append(defs, strings.TrimSpace(createSQL[start:token.start]))
+				return defs, strings.TrimSpace(createSQL[token.end:]), true
+			}
+		case ",":
+			if depth == 1 {
+				defs = append(defs, strings.TrimSpace(createSQL[start:token.start]))
+				start = token.end
+			}
+		}
+	}
+
+	return nil, "", false
+}
+
+// definitionColumn returns the column a table body definition declares, or
+// false when the definition is a table constraint.
+func definitionColumn(def string) (string, bool) {
+	tokens := sqlTokens(def)
+	if len(tokens) == 0 {
+		return "", false
+	}
+	first := tokens[0]
+	quoted := first.end-first.start != len(first.text)
+	if !quoted {
+		switch strings.ToUpper(first.text) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			return "", false
+		}
+	}
+
+	return first.text, true
+}
+
+// canAddColumn reports whether ALTER TABLE ADD COLUMN accepts the column:
+// it may not be part of the primary key, NOT NULL requires a default, and
+// the default must be a constant rather than CURRENT_* or an expression in
+// parentheses. Generated columns are rebuilt because table_xinfo does not
+// report their expression.
+func canAddColumn(column ColumnInfo) bool {
+	if column.PrimaryKey > 0 || column.Generated() != "" {
+		return false
+	}
+	if value := strings.ToUpper(strings.TrimSpace(column.DefaultValue.String)); strings.HasPrefix(value, "(") || strings.HasPrefix(value, "CURRENT_") {
+		return false
+	}
+
+	return !column.NotNull || column.DefaultValue.Valid
+}
+
+func normalizeSQL(stmt string) string {
+	return strings.Join(strings.Fields(stmt), " ")
+}
+
+// canonicalSQL normalizes a statement for comparison only: whitespace is
+// collapsed, identifier quotes are dropped and the text is lowercased, so the
+// quoting ALTER TABLE ADD COLUMN leaves behind does not count as a change.
+func canonicalSQL(stmt string) string {
+	stmt = strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "").Replace(stmt)
+
+	return strings.ToLower(normalizeSQL(stmt))
+}
+
+type DifferenceKind string
+
+const (
+	TableOnlyInA            DifferenceKind = "table only in a"
+	TableOnlyInB            DifferenceKind = "table only in b"
+	ColumnOnlyInA           DifferenceKind = "column only in a"
+	ColumnOnlyInB           DifferenceKind = "column only in b"
+	ColumnTypeDiffers       DifferenceKind = "column type differs"
+	ColumnNotNullDiffers    DifferenceKind = "column not null differs"
+	ColumnDefaultDiffers    DifferenceKind = "column default differs"
+	ColumnPrimaryKeyDiffers DifferenceKind = "column primary key differs"
+)
+
+// SchemaDifference describes one way two schemas differ. Object is a table
+// name or "table.column"; A and B hold each side's value, empty when the
+// object is absent on that side.
+type SchemaDifference struct {
+	Object string
+	Kind   DifferenceKind
+	A      string
+	B      string
+}
+
+func (d SchemaDifference) String() string {
+	return fmt.Sprintf("%s: %s (a=%q, b=%q)", d.Object, d.Kind, d.A, d.B)
+}
+
+// DiffSchema compares the tables and columns of a and b, in both directions.
+// Differences are ordered by table, then by column position in a.
+func DiffSchema(a, b *sqlx.DB) ([]SchemaDifference, error) {
+	aTables, err := ListTables(a)
+	if err != nil {
+		return nil, err
+	}
+	bTables, err := ListTables(b)
+	if err != nil {
+		return nil, err
+	}
+
+	inB := make(map[string]string, len(bTables))
+	for _, table := range bTables {
+		inB[strings.ToLower(table)] = table
+	}
+	inA := make(map[string]bool, len(aTables))
+
+	diffs := []SchemaDifference{}
+	for _, table := range aTables {
+		inA[strings.ToLower(table)] = true
+		bTable, ok := inB[strings.ToLower(table)]
+		if !ok {
+			diffs = append(diffs, SchemaDifference{Object: table, Kind: TableOnlyInA, A: table})
+			continue
+		}
+
+		columnDiffs, err := diffColumns(a, b, table, bTable)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, columnDiffs...)
+	}
+	for _, table := range bTables {
+		if !inA[strings.ToLower(table)] {
+			diffs = append(diffs, SchemaDifference{Object: table, Kind: TableOnlyInB, B: table})
+		}
+	}
+
+	return diffs, nil
+}
+
+func diffColumns(a, b *sqlx.DB, aTable, bTable string) ([]SchemaDifference, error) {
+	aColumns, err := GetTableColumns(a, aTable)
+	if err != nil {
+		return nil, err
+	}
+	bColumns, err := GetTableColumns(b, bTable)
+	if err != nil {
+		return nil, err
+	}
+
+	bByName := make(map[string]ColumnInfo, len(bColumns))
+	for _, column := range bColumns {
+		bByName[strings.ToLower(column.Name)] = column
+	}
+	seen := make(map[string]bool, len(aColumns))
+
+	var diffs []SchemaDifference
+	for _, ac := range aColumns {
+		seen[strings.ToLower(ac.Name)] = true
+		object := aTable + "." + ac.Name
+		bc, ok := bByName[strings.ToLower(ac.Name)]
+		if !ok {
+			diffs = append(diffs, SchemaDifference{Object: object, Kind: ColumnOnlyInA, A: ac.Type})
+			continue
+		}
+		if !strings.EqualFold(ac.Type, bc.Type) {
+			diffs = append(diffs, SchemaDifference{Object: object, Kind: ColumnTypeDiffers, A: ac.Type, B: bc.Type})
+		}
+		if ac.NotNull != bc.NotNull {
+			diffs = append(diffs, SchemaDifference{Object: object, Kind: ColumnNotNullDiffers, A: fmt.Sprint(ac.NotNull), B: fmt.Sprint(bc.NotNull)})
+		}
+		if ac.DefaultValue != bc.DefaultValue {
+			diffs = append(diffs, SchemaDifference{Object: object, Kind: ColumnDefaultDiffers, A: ac.DefaultValue.String, B: bc.DefaultValue.String})
+		}
+		if ac.PrimaryKey != bc.PrimaryKey {
+			diffs = append(diffs, SchemaDifference{Object: object, Kind: ColumnPrimaryKeyDiffers, A: fmt.Sprint(ac.PrimaryKey), B: fmt.Sprint(bc.PrimaryKey)})
+		}
+	}
+	for _, bc := range bColumns {
+		if !seen[strings.ToLower(bc.Name)] {
+			diffs = append(diffs, SchemaDifference{Object: aTable + "." + bc.Name, Kind: ColumnOnlyInB, B: bc.Type})
+		}
+	}
+
+	return diffs, nil
+}
@@ -0,0 +1,214 @@
+package sqlite_base
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateMigration_AddsTablesColumnsAndIndexes(t *testing.T) {
+	t.Parallel()
+
+	from := newTestDB(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	to := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT NOT NULL DEFAULT '')",
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id), title TEXT)",
+		"CREATE INDEX idx_posts_user_id ON posts (user_id)",
+	)
+
+	script, err := GenerateMigration(from, to)
+	if err != nil {
+		t.Fatalf("generate migration failed: %v", err)
+	}
+
+	for _, want := range []string{
+		`ALTER TABLE "users" ADD COLUMN email TEXT NOT NULL DEFAULT '';`,
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id), title TEXT);",
+		"CREATE INDEX idx_posts_user_id ON posts (user_id);",
+	} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("expected script to contain %q, got:\n%s", want, script)
+		}
+	}
+
+	if _, err := from.Exec(script); err != nil {
+		t.Fatalf("apply generated script failed: %v", err)
+	}
+	script, err = GenerateMigration(from, to)
+	if err != nil {
+		t.Fatalf("generate migration failed: %v", err)
+	}
+	if script != "" {
+		t.Fatalf("expected no changes after applying script, got:\n%s", script)
+	}
+}
+
+func TestGenerateMigration_RebuildsDroppedColumns(t *testing.T) {
+	t.Parallel()
+
+	from := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, legacy TEXT)",
+		"INSERT INTO users (name, legacy) VALUES ('alice', 'x')",
+		"CREATE TABLE sessions (id TEXT PRIMARY KEY)",
+	)
+	to := newTestDB(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+
+	script, err := GenerateMigration(from, to)
+	if err != nil {
+		t.Fatalf("generate migration failed: %v", err)
+	}
+	for _, want := range []string{
+		`DROP TABLE "sessions";`,
+		"-- rebuild users",
+		`INSERT INTO "users_new" ("id", "name") SELECT "id", "name" FROM "users";`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("expected script to contain %q, got:\n%s", want, script)
+		}
+	}
+
+	if _, err := from.Exec(script); err != nil {
+		t.Fatalf("apply generated script failed: %v", err)
+	}
+	if err := ValidateExactColumns(from, map[string][]string{"users": {"id", "name"}}); err != nil {
+		t.Fatalf("rebuilt table has wrong columns: %v", err)
+	}
+}
+
+func TestGenerateMigration_KeepsAddedColumnClauses(t *testing.T) {
+	t.Parallel()
+
+	from := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT)",
+	)
+	to := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT, author_id INTEGER REFERENCES users (id), code TEXT COLLATE NOCASE CHECK (length(code) < 10))",
+	)
+
+	script, err := GenerateMigration(from, to)
+	if err != nil {
+		t.Fatalf("generate migration failed: %v", err)
+	}
+	for _, want := range []string{
+		`ALTER TABLE "posts" ADD COLUMN author_id INTEGER REFERENCES users (id);`,
+		`ALTER TABLE "posts" ADD COLUMN code TEXT COLLATE NOCASE CHECK (length(code) < 10);`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("expected script to contain %q, got:\n%s", want, script)
+		}
+	}
+
+	if _, err := from.Exec(script); err != nil {
+		t.Fatalf("apply generated script failed: %v", err)
+	}
+	script, err = GenerateMigration(from, to)
+	if err != nil {
+		t.Fatalf("generate migration failed: %v", err)
+	}
+	if script != "" {
+		t.Fatalf("expected no changes after applying script, got:\n%s", script)
+	}
+}
+
+func TestGenerateMigration_RebuildsWhenConstraintsChangeWithAddedColumn(t *testing.T) {
+	t.Parallel()
+
+	from := newTestDB(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)")
+	to := newTestDB(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, name TEXT, UNIQUE (email))")
+
+	script, err := GenerateMigration(from, to)
+	if err != nil {
+		t.Fatalf("generate migration failed: %v", err)
+	}
+	if strings.Contains(script, "ADD COLUMN") || !strings.Contains(script, "-- rebuild users") {
+		t.Fatalf("expected a rebuild, got:\n%s", script)
+	}
+
+	if _, err := from.Exec(script); err != nil {
+		t.Fatalf("apply generated script failed: %v", err)
+	}
+	script, err = GenerateMigration(from, to)
+	if err != nil || script != "" {
+		t.Fatalf("expected no changes after applying script, got %q, %v", script, err)
+	}
+}
+
+func TestGenerateMigration_RebuildKeepsChildRowsAndTriggers(t *testing.T) {
+	t.Parallel()
+
+	from := newTestDB(t,
+		"PRAGMA foreign_keys = ON",
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, legacy TEXT)",
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id) ON DELETE CASCADE)",
+		"CREATE TABLE audit (name TEXT)",
+		"CREATE TRIGGER users_audit AFTER INSERT ON users BEGIN INSERT INTO audit (name) VALUES (new.name); END",
+		"INSERT INTO users (name) VALUES ('alice')",
+		"INSERT INTO posts (user_id) VALUES (1)",
+	)
+	to := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id) ON DELETE CASCADE)",
+		"CREATE TABLE audit (name TEXT)",
+	)
+
+	script, err := GenerateMigration(from, to)
+	if err != nil {
+		t.Fatalf("generate migration failed: %v", err)
+	}
+	for _, want := range []string{"PRAGMA foreign_keys = OFF;", "PRAGMA foreign_key_check;", "PRAGMA foreign_keys = ON;", "CREATE TRIGGER users_audit"} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("expected script to contain %q, got:\n%s", want, script)
+		}
+	}
+
+	if _, err := from.Exec(script); err != nil {
+		t.Fatalf("apply generated script failed: %v", err)
+	}
+	if n, err := Count(from, "posts", ""); err != nil || n != 1 {
+		t.Fatalf("expected the child row to survive the rebuild, got %d, %v", n, err)
+	}
+	from.MustExec("INSERT INTO users (name) VALUES ('bob')")
+	if n, err := Count(from, "audit", ""); err != nil || n != 2 {
+		t.Fatalf("expected the trigger to survive the rebuild, got %d audit rows, %v", n, err)
+	}
+}
+
+func TestDiffSchema(t *testing.T) {
+	t.Parallel()
+
+	a := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, legacy TEXT)",
+		"CREATE TABLE sessions (id TEXT PRIMARY KEY)",
+	)
+	b := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(64), email TEXT)",
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY)",
+	)
+
+	diffs, err := DiffSchema(a, b)
+	if err != nil {
+		t.Fatalf("diff schema failed: %v", err)
+	}
+
+	want := []SchemaDifference{
+		{Object: "sessions", Kind: TableOnlyInA, A: "sessions"},
+		{Object: "users.name", Kind: ColumnTypeDiffers, A: "TEXT", B: "VARCHAR(64)"},
+		{Object: "users.name", Kind: ColumnNotNullDiffers, A: "true", B: "false"},
+		{Object: "users.legacy", Kind: ColumnOnlyInA, A: "TEXT"},
+		{Object: "users.email", Kind: ColumnOnlyInB, B: "TEXT"},
+		{Object: "posts", Kind: TableOnlyInB, B: "posts"},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Fatalf("unexpected differences:\n got: %v\nwant: %v", diffs, want)
+	}
+
+	same, err := DiffSchema(a, a)
+	if err != nil {
+		t.Fatalf("diff schema failed: %v", err)
+	}
+	if len(same) != 0 {
+		t.Fatalf("expected no differences, got %v", same)
+	}
+}
@@ -0,0 +1,132 @@
+package sqlite_base
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SynchronousMode is a PRAGMA synchronous setting.
+type SynchronousMode string
+
+const (
+	// SynchronousOff hands writes to the OS without syncing. It is the
+	// fastest mode but a power loss or OS crash can corrupt the database.
+	SynchronousOff SynchronousMode = "OFF"
+	// SynchronousNormal syncs at critical moments only. It is safe from
+	// corruption in WAL mode, where a power loss can only roll back the most
+	// recent transactions.
+	SynchronousNormal SynchronousMode = "NORMAL"
+	SynchronousFull   SynchronousMode = "FULL"
+	SynchronousExtra  SynchronousMode = "EXTRA"
+)
+
+// DSN describes a go-sqlite3 data source name. String renders it with the
+// driver's parameter names and correct escaping, switching to a file: URI
+// when SQLite URI parameters (mode, immutable, cache) are needed.
+type DSN struct {
+	Path string
+	// Mode is the URI open mode: "ro", "rw", "rwc" or "memory".
+	Mode        string
+	Immutable   bool
+	CacheShared bool
+	ForeignKeys bool
+	BusyTimeout time.Duration
+	// JournalMode is DELETE, TRUNCATE, PERSIST, MEMORY, WAL or OFF.
+	JournalMode string
+	Synchronous SynchronousMode
+	// CacheSize follows PRAGMA cache_size: pages when positive, KiB when
+	// negative.
+	CacheSize int
+	// TxLock is the BEGIN behaviour: "deferred", "immediate" or "exclusive".
+	TxLock string
+	// Params holds any further driver or URI parameters verbatim.
+	Params url.Values
+}
+
+func (d DSN) Validate() error {
+	switch strings.ToLower(d.Mode) {
+	case "", "ro", "rw", "rwc", "memory":
+	default:
+		return fmt.Errorf("unknown open mode %q", d.Mode)
+	}
+	switch strings.ToUpper(d.JournalMode) {
+	case "", "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF":
+	default:
+		return fmt.Errorf("unknown journal mode %q", d.JournalMode)
+	}
+	switch SynchronousMode(strings.ToUpper(string(d.Synchronous))) {
+	case "", SynchronousOff, SynchronousNormal, SynchronousFull, SynchronousExtra:
+	default:
+		return fmt.Errorf("unknown synchronous mode %q", d.Synchronous)
+	}
+	switch strings.ToLower(d.TxLock) {
+	case "", "deferred", "immediate", "exclusive":
+	default:
+		return fmt.Errorf("unknown transaction lock %q", d.TxLock)
+	}
+	if d.BusyTimeout < 0 {
+		return fmt.Errorf("busy timeout must not be negative, got %s", d.BusyTimeout)
+	}
+
+	return nil
+}
+
+func (d DSN) String() string {
+	params := url.Values{}
+	for key, values := range d.Params {
+		params[key] = append([]string(nil), values...)
+	}
+
+	useURI := strings.HasPrefix(d.Path, "file:") || strings.ContainsAny(d.Path, "?#")
+	if d.Mode != "" {
+		params.Set("mode", strings.ToLower(d.Mode))
+		useURI = true
+	}
+	if d.Immutable {
+		params.Set("immutable", "1")
+		useURI = true
+	}
+	if d.CacheShared {
+		params.Set("cache", "shared")
+		useURI = true
+	}
+	if d.ForeignKeys {
+		params.Set("_foreign_keys", "1")
+	}
+	if d.BusyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(d.BusyTimeout.Milliseconds(), 10))
+	}
+	if d.JournalMode != "" {
+		params.Set("_journal_mode", strings.ToUpper(d.JournalMode))
+	}
+	if d.Synchronous != "" {
+		params.Set("_synchronous", strings.ToUpper(string(d.Synchronous)))
+	}
+	if d.CacheSize != 0 {
+		params.Set("_cache_size", strconv.Itoa(d.CacheSize))
+	}
+	if d.TxLock != "" {
+		params.Set("_txlock", strings.ToLower(d.TxLock))
+	}
+
+	path := d.Path
+	if useURI {
+		path = fileURI(path)
+	}
+	if len(params) == 0 {
+		return path
+	}
+
+	return path + "?" + params.Encode()
+}
+
+func fileURI(path string) string {
+	if strings.HasPrefix(path, "file:") {
+		return path
+	}
+
+	return "file:" + strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(path)
+}
@@ -0,0 +1,80 @@
+package sqlite_base
+
+import (
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDSN_String(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		dsn  DSN
+		want string
+	}{
+		{"plain path", DSN{Path: "app.db"}, "app.db"},
+		{
+			"driver params",
+			DSN{Path: "/var/lib/app/data.db", ForeignKeys: true, BusyTimeout: 5 * time.Second, JournalMode: "wal", Synchronous: SynchronousNormal},
+			"/var/lib/app/data.db?_busy_timeout=5000&_foreign_keys=1&_journal_mode=WAL&_synchronous=NORMAL",
+		},
+		{"uri params", DSN{Path: "data.db", Mode: "ro", CacheShared: true}, "file:data.db?cache=shared&mode=ro"},
+		{"escaped path", DSN{Path: "dir?/a#b%.db"}, "file:dir%3f/a%23b%25.db"},
+		{"extra params", DSN{Path: "app.db", TxLock: "IMMEDIATE", Params: url.Values{"_loc": {"auto"}}}, "app.db?_loc=auto&_txlock=immediate"},
+	}
+	for _, tt := range tests {
+		if got := tt.dsn.String(); got != tt.want {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestDSN_Validate(t *testing.T) {
+	t.Parallel()
+
+	invalid := []DSN{
+		{Path: "a.db", Mode: "append"},
+		{Path: "a.db", JournalMode: "fast"},
+		{Path: "a.db", Synchronous: "sometimes"},
+		{Path: "a.db", TxLock: "eager"},
+		{Path: "a.db", BusyTimeout: -time.Second},
+	}
+	for _, dsn := range invalid {
+		if err := dsn.Validate(); err == nil {
+			t.Fatalf("expected %+v to be invalid", dsn)
+		}
+	}
+}
+
+func TestOpen_WithDSN(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{DSN: DSN{
+		Path:        filepath.Join(t.TempDir(), "app #1.sqlite"),
+		ForeignKeys: true,
+		JournalMode: "WAL",
+	}})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	var journalMode string
+	if err := db.Get(&journalMode, "PRAGMA journal_mode"); err != nil {
+		t.Fatalf("read journal mode failed: %v", err)
+	}
+	if journalMode != "wal" {
+		t.Fatalf("expected wal journal mode, got %s", journalMode)
+	}
+
+	var foreignKeys int
+	if err := db.Get(&foreignKeys, "PRAGMA foreign_keys"); err != nil {
+		t.Fatalf("read foreign keys failed: %v", err)
+	}
+	if foreignKeys != 1 {
+		t.Fatalf("expected foreign keys on, got %d", foreignKeys)
+	}
+}
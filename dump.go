@@ -0,0 +1,94 @@
+package sqlite_base
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DumpSchema returns the stored SQL of every table, index, view and trigger,
+// each terminated by a semicolon, like the sqlite3 shell's .schema command.
+// Tables come first with referenced tables before the tables that reference
+// them, then indexes, views in creation order, and triggers. Internal objects
+// such as autoindexes are omitted.
+func DumpSchema(db *sqlx.DB) (string, error) {
+	tables, err := tablesInDependencyOrder(db)
+	if err != nil {
+		return "", err
+	}
+
+	var stmts []string
+	for _, table := range tables {
+		createSQL, err := tableSQL(db, table)
+		if err != nil {
+			return "", err
+		}
+		stmts = append(stmts, createSQL)
+	}
+
+	queries := []string{
+		`SELECT sql FROM sqlite_master WHERE type = 'index' AND sql IS NOT NULL ORDER BY tbl_name, name`,
+		`SELECT sql FROM sqlite_master WHERE type = 'view' ORDER BY rowid`,
+		`SELECT sql FROM sqlite_master WHERE type = 'trigger' ORDER BY tbl_name, name`,
+	}
+	for _, query := range queries {
+		var objects []string
+		if err := db.Select(&objects, query); err != nil {
+			return "", fmt.Errorf("read schema: %w", err)
+		}
+		stmts = append(stmts, objects...)
+	}
+
+	if len(stmts) == 0 {
+		return "", nil
+	}
+
+	return strings.Join(stmts, ";\n") + ";\n", nil
+}
+
+// tablesInDependencyOrder sorts the tables so that every table follows the
+// tables its foreign keys reference. Cycles are broken by name order.
+func tablesInDependencyOrder(db *sqlx.DB) ([]string, error) {
+	tables, err := ListTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(tables))
+	for _, table := range tables {
+		names[strings.ToLower(table)] = table
+	}
+	parents := make(map[string][]string, len(tables))
+	for _, table := range tables {
+		var refs []string
+		if err := db.Select(&refs, `SELECT DISTINCT "table" FROM pragma_foreign_key_list(?)`, table); err != nil {
+			return nil, fmt.Errorf("query foreign keys for %s: %w", table, err)
+		}
+		for _, ref := range refs {
+			if parent, ok := names[strings.ToLower(ref)]; ok && parent != table {
+				parents[table] = append(parents[table], parent)
+			}
+		}
+	}
+
+	ordered := make([]string, 0, len(tables))
+	state := make(map[string]int, len(tables))
+	var visit func(string)
+	visit = func(table string) {
+		if state[table] != 0 {
+			return
+		}
+		state[table] = 1
+		for _, parent := range parents[table] {
+			visit(parent)
+		}
+		state[table] = 2
+		ordered = append(ordered, table)
+	}
+	for _, table := range tables {
+		visit(table)
+	}
+
+	return ordered, nil
+}
@@ -0,0 +1,34 @@
+package sqlite_base
+
+import "testing"
+
+func TestDumpSchema(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE accounts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id))",
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE)",
+		"CREATE INDEX idx_accounts_user_id ON accounts (user_id)",
+		"CREATE VIEW account_users AS SELECT a.id, u.email FROM accounts a JOIN users u ON u.id = a.user_id",
+		"CREATE TRIGGER users_cleanup AFTER DELETE ON users BEGIN DELETE FROM accounts WHERE user_id = OLD.id; END",
+	)
+
+	schema, err := DumpSchema(db)
+	if err != nil {
+		t.Fatalf("dump schema failed: %v", err)
+	}
+
+	want := "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE);\n" +
+		"CREATE TABLE accounts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id));\n" +
+		"CREATE INDEX idx_accounts_user_id ON accounts (user_id);\n" +
+		"CREATE VIEW account_users AS SELECT a.id, u.email FROM accounts a JOIN users u ON u.id = a.user_id;\n" +
+		"CREATE TRIGGER users_cleanup AFTER DELETE ON users BEGIN DELETE FROM accounts WHERE user_id = OLD.id; END;\n"
+	if schema != want {
+		t.Fatalf("unexpected schema dump:\n%s", schema)
+	}
+
+	restored := newTestDB(t)
+	if _, err := restored.Exec(schema); err != nil {
+		t.Fatalf("replaying dump failed: %v", err)
+	}
+}
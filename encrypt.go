@@ -0,0 +1,146 @@
+package sqlite_base
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrEncryptionUnsupported is returned when an encryption key is supplied but
+// the linked SQLite library is not SQLCipher. The stock go-sqlite3 build
+// ignores PRAGMA key, so Open refuses to continue rather than silently
+// creating an unencrypted file.
+var ErrEncryptionUnsupported = errors.New("sqlite library does not support encryption (build against SQLCipher)")
+
+// ErrEncryptionKeyInvalid is returned by RotateKey when the old key does not
+// decrypt the database.
+var ErrEncryptionKeyInvalid = errors.New("encryption key does not decrypt the database")
+
+// RotateKey re-encrypts the SQLCipher database at path from oldKey to newKey
+// and verifies the result by reopening it with newKey. A wrong oldKey fails
+// with ErrEncryptionKeyInvalid and leaves the file untouched. SQLCipher
+// rekeys inside a transaction, so a failed rekey keeps the old key; if the
+// database cannot be reopened with newKey afterwards, RotateKey reports
+// which key still opens it. No other process may use the database while the
+// key changes.
+func RotateKey(path, oldKey, newKey string) error {
+	if oldKey == "" || newKey == "" {
+		return errors.New("old and new keys are required")
+	}
+	if oldKey == newKey {
+		return errors.New("new key must differ from the old key")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("stat database file: %w", err)
+	}
+
+	db, err := openWithKey(path, oldKey)
+	if err != nil {
+		return err
+	}
+	db.SetMaxOpenConns(1)
+	if err := Rekey(db, newKey); err != nil {
+		_ = db.Close()
+		return err
+	}
+	if err := db.Close(); err != nil {
+		return fmt.Errorf("close database: %w", err)
+	}
+
+	verify, err := openWithKey(path, newKey)
+	if err == nil {
+		return verify.Close()
+	}
+	if old, oldErr := openWithKey(path, oldKey); oldErr == nil {
+		_ = old.Close()
+		return fmt.Errorf("rotate key: database still uses the old key: %w", err)
+	}
+
+	return fmt.Errorf("rotate key: database opens with neither key: %w", err)
+}
+
+// openWithKey opens path with key, reporting a key that does not decrypt
+// the file as ErrEncryptionKeyInvalid rather than as a corrupt database.
+func openWithKey(path, key string) (*sqlx.DB, error) {
+	db, err := Open(Config{Path: path, EncryptionKey: key})
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrNotADB {
+			return nil, ErrEncryptionKeyInvalid
+		}
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Rekey re-encrypts a SQLCipher database opened with Config.EncryptionKey
+// using newKey. It must not race with other statements: connections already
+// in the pool keep the old key, so callers should close and reopen the
+// database afterwards. Rekey returns ErrEncryptionUnsupported when the
+// driver lacks SQLCipher.
+func Rekey(db *sqlx.DB, newKey string) error {
+	if newKey == "" {
+		return errors.New("new key is required")
+	}
+
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var version string
+	if err := conn.GetContext(ctx, &version, "PRAGMA cipher_version"); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEncryptionUnsupported
+		}
+		return fmt.Errorf("query cipher version: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA rekey = "+quoteKey(newKey)); err != nil {
+		return fmt.Errorf("rekey database: %w", err)
+	}
+
+	if c, ok := db.Driver().(*connector); ok {
+		c.setKey(newKey)
+	}
+
+	return nil
+}
+
+// applyKey runs PRAGMA key as the first statement on a new connection and
+// verifies that the library actually understood it.
+func applyKey(conn *sqlite3.SQLiteConn, key string) error {
+	if _, err := conn.Exec("PRAGMA key = "+quoteKey(key), nil); err != nil {
+		return fmt.Errorf("apply encryption key: %w", err)
+	}
+
+	rows, err := conn.Query("PRAGMA cipher_version", nil)
+	if err != nil {
+		return fmt.Errorf("query cipher version: %w", err)
+	}
+	defer rows.Close()
+
+	dest := make([]driver.Value, len(rows.Columns()))
+	if err := rows.Next(dest); err != nil {
+		if errors.Is(err, io.EOF) {
+			return ErrEncryptionUnsupported
+		}
+		return fmt.Errorf("query cipher version: %w", err)
+	}
+
+	return nil
+}
+
+func quoteKey(key string) string {
+	return "'" + strings.ReplaceAll(key, "'", "''") + "'"
+}
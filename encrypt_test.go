@@ -0,0 +1,69 @@
+package sqlite_base
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpen_EncryptionKeyRequiresSQLCipher(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "secret.sqlite")
+	db, err := Open(Config{Path: path, EncryptionKey: "s3cret"})
+	if err == nil {
+		_ = db.Close()
+		t.Skip("driver supports SQLCipher")
+	}
+	if !errors.Is(err, ErrEncryptionUnsupported) {
+		t.Fatalf("expected ErrEncryptionUnsupported, got %v", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+		t.Fatalf("expected no unencrypted data to be written, got %d bytes", info.Size())
+	}
+}
+
+func TestRekey_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t)
+	if err := Rekey(db, "new"); !errors.Is(err, ErrEncryptionUnsupported) {
+		t.Fatalf("expected ErrEncryptionUnsupported, got %v", err)
+	}
+}
+
+func TestRotateKey(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "secret.sqlite")
+	if err := RotateKey(path, "old", "old"); err == nil {
+		t.Fatal("expected identical keys to be rejected")
+	}
+	if err := RotateKey(path, "old", "new"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected missing database error, got %v", err)
+	}
+
+	db, err := Open(Config{Path: path, EncryptionKey: "old"})
+	if err != nil {
+		if !errors.Is(err, ErrEncryptionUnsupported) {
+			t.Fatalf("expected ErrEncryptionUnsupported, got %v", err)
+		}
+		if err := RotateKey(path, "old", "new"); !errors.Is(err, ErrEncryptionUnsupported) {
+			t.Fatalf("expected ErrEncryptionUnsupported from rotate, got %v", err)
+		}
+		t.Skip("driver does not support SQLCipher")
+	}
+	db.MustExec("CREATE TABLE secrets (value TEXT)")
+	_ = db.Close()
+
+	if err := RotateKey(path, "wrong", "new"); !errors.Is(err, ErrEncryptionKeyInvalid) {
+		t.Fatalf("expected ErrEncryptionKeyInvalid, got %v", err)
+	}
+	if err := RotateKey(path, "old", "new"); err != nil {
+		t.Fatalf("rotate key failed: %v", err)
+	}
+	if _, err := Open(Config{Path: path, EncryptionKey: "old"}); err == nil {
+		t.Fatal("expected old key to stop working")
+	}
+}
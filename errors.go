@@ -0,0 +1,80 @@
+package sqlite_base
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Sentinel errors wrapped by ValidationError, for use with errors.Is.
+var (
+	ErrTableMissing       = errors.New("table is missing")
+	ErrColumnMissing      = errors.New("column is missing")
+	ErrColumnTypeMismatch = errors.New("column type mismatch")
+	ErrIndexMissing       = errors.New("index is missing")
+	ErrForeignKeyMissing  = errors.New("foreign key is missing")
+)
+
+// ValidationError describes a schema validation failure. Err is one of the
+// sentinel errors above; the other fields are set as relevant: Name holds the
+// index or foreign key, and Expected and Actual the column types.
+type ValidationError struct {
+	Err      error
+	Table    string
+	Column   string
+	Name     string
+	Expected string
+	Actual   string
+}
+
+func (e *ValidationError) Error() string {
+	switch e.Err {
+	case ErrTableMissing:
+		return fmt.Sprintf("table %s is missing", e.Table)
+	case ErrColumnMissing:
+		return fmt.Sprintf("table %s: column %s is missing", e.Table, e.Column)
+	case ErrColumnTypeMismatch:
+		return fmt.Sprintf("table %s: column %s expected type %s, got %s", e.Table, e.Column, e.Expected, e.Actual)
+	case ErrIndexMissing:
+		return fmt.Sprintf("index %s is missing", e.Name)
+	case ErrForeignKeyMissing:
+		return fmt.Sprintf("foreign key %s is missing", e.Name)
+	}
+
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// IsBusy reports whether err wraps a SQLite error whose primary result code
+// is SQLITE_BUSY, including extended codes such as SQLITE_BUSY_SNAPSHOT.
+func IsBusy(err error) bool {
+	return hasErrorCode(err, sqlite3.ErrBusy)
+}
+
+// IsLocked reports whether err wraps a SQLite error whose primary result
+// code is SQLITE_LOCKED, such as a conflict on a shared-cache table.
+func IsLocked(err error) bool {
+	return hasErrorCode(err, sqlite3.ErrLocked)
+}
+
+// IsConstraintViolation reports whether err wraps a SQLITE_CONSTRAINT error:
+// a UNIQUE, PRIMARY KEY, NOT NULL, CHECK or FOREIGN KEY violation. The
+// extended code tells them apart.
+func IsConstraintViolation(err error) bool {
+	return hasErrorCode(err, sqlite3.ErrConstraint)
+}
+
+// hasErrorCode compares result codes rather than messages. go-sqlite3 sets
+// Code to the primary code, the low byte of ExtendedCode.
+func hasErrorCode(err error, code sqlite3.ErrNo) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	return sqliteErr.Code == code || sqlite3.ErrNo(sqliteErr.ExtendedCode&0xff) == code
+}
@@ -0,0 +1,90 @@
+package sqlite_base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestValidationError_IsAndAs(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)")
+
+	tests := []struct {
+		name    string
+		schema  Schema
+		want    error
+		message string
+	}{
+		{"table", Schema{Columns: map[string]map[string]string{"orders": {"id": "INTEGER"}}}, ErrTableMissing, "table orders is missing"},
+		{"column", Schema{Columns: map[string]map[string]string{"users": {"name": "TEXT"}}}, ErrColumnMissing, "table users: column name is missing"},
+		{"type", Schema{Columns: map[string]map[string]string{"users": {"email": "BLOB"}}}, ErrColumnTypeMismatch, "table users: column email expected type BLOB, got TEXT"},
+		{"index", Schema{Indexes: []Index{{Name: "idx_users_email"}}}, ErrIndexMissing, "index idx_users_email is missing"},
+	}
+	for _, tt := range tests {
+		err := ValidateSchema(db, tt.schema)
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+		if err.Error() != tt.message {
+			t.Fatalf("%s: expected message %q, got %q", tt.name, tt.message, err.Error())
+		}
+	}
+
+	_, err := Open(Config{
+		Path:   filepath.Join(t.TempDir(), "app.sqlite"),
+		Schema: Schema{Columns: map[string]map[string]string{"orders": {"id": "INTEGER"}}},
+	})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Table != "orders" {
+		t.Fatalf("expected ValidationError for orders through Open, got %v", err)
+	}
+}
+
+func TestErrorCodeHelpers(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{
+		Path:        filepath.Join(t.TempDir(), "app.sqlite"),
+		BusyHandler: func(int) bool { return false },
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	db.MustExec("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE)")
+	db.MustExec("INSERT INTO users (email) VALUES ('a@example.com')")
+
+	_, err = db.Exec("INSERT INTO users (email) VALUES ('a@example.com')")
+	if !IsConstraintViolation(fmt.Errorf("insert user: %w", err)) || IsBusy(err) || IsLocked(err) {
+		t.Fatalf("expected only a constraint violation, got %v", err)
+	}
+
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		t.Fatalf("checkout failed: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatalf("begin failed: %v", err)
+	}
+	defer func() { _, _ = conn.ExecContext(ctx, "ROLLBACK") }()
+
+	_, err = db.Exec("INSERT INTO users (email) VALUES ('b@example.com')")
+	if !IsBusy(err) || IsConstraintViolation(err) {
+		t.Fatalf("expected busy error, got %v", err)
+	}
+
+	if !IsLocked(sqlite3.Error{ExtendedCode: sqlite3.ErrLockedSharedCache}) {
+		t.Fatal("expected extended locked code to be detected")
+	}
+	if IsBusy(errors.New("database is locked")) || IsBusy(nil) {
+		t.Fatal("expected plain errors not to match")
+	}
+}
@@ -0,0 +1,104 @@
+package sqlite_base
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrFTS5Unsupported is returned when the linked SQLite library was built
+// without FTS5. go-sqlite3 only compiles it in with the sqlite_fts5 (or
+// fts5) build tag.
+var ErrFTS5Unsupported = errors.New("sqlite was built without FTS5; build with -tags sqlite_fts5")
+
+// FTS5Options are the optional arguments of an FTS5 table. Empty fields are
+// left to FTS5's defaults.
+type FTS5Options struct {
+	// Tokenize selects the tokenizer, e.g. "porter unicode61".
+	Tokenize string
+	// Prefix lists the prefix lengths to index, e.g. "2 3".
+	Prefix string
+	// Content names an external content table, and ContentRowid its
+	// integer key column; Content "" stores the content in the index.
+	Content      string
+	ContentRowid string
+	// Detail is full, column or none.
+	Detail string
+}
+
+var fts5ModulePattern = regexp.MustCompile(`(?is)^\s*CREATE\s+VIRTUAL\s+TABLE\s+.*?\bUSING\s+fts5\b`)
+
+// FTS5Supported reports whether the linked SQLite library includes FTS5.
+func FTS5Supported(db *sqlx.DB) (bool, error) {
+	var used bool
+	if err := db.Get(&used, "SELECT sqlite_compileoption_used('ENABLE_FTS5')"); err != nil {
+		return false, fmt.Errorf("query compile options: %w", err)
+	}
+
+	return used, nil
+}
+
+// CreateFTS5Table creates the FTS5 virtual table name indexing columns. It
+// returns ErrFTS5Unsupported when FTS5 is not compiled in.
+func CreateFTS5Table(db *sqlx.DB, name string, columns []string, opts FTS5Options) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("table name is required")
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("fts5 table %s needs at least one column", name)
+	}
+	supported, err := FTS5Supported(db)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return ErrFTS5Unsupported
+	}
+
+	args := []string{quoteIdents(columns)}
+	for _, option := range []struct{ name, value string }{
+		{"tokenize", opts.Tokenize},
+		{"prefix", opts.Prefix},
+		{"content", opts.Content},
+		{"content_rowid", opts.ContentRowid},
+		{"detail", opts.Detail},
+	} {
+		if option.value != "" {
+			args = append(args, fmt.Sprintf("%s = '%s'", option.name, strings.ReplaceAll(option.value, "'", "''")))
+		}
+	}
+
+	stmt := fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(%s)", quoteIdent(name), strings.Join(args, ", "))
+	if _, err := db.Exec(stmt); err != nil {
+		return fmt.Errorf("create fts5 table %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// IsFTS5Table reports whether table is an FTS5 virtual table. Such tables are
+// stored with type "table" in sqlite_master, distinguished only by their
+// USING fts5 clause.
+func IsFTS5Table(db *sqlx.DB, table string) (bool, error) {
+	createSQL, err := tableSQL(db, table)
+	if err != nil {
+		return false, err
+	}
+
+	return fts5ModulePattern.MatchString(createSQL), nil
+}
+
+// Search runs an FTS5 MATCH query against table and returns the matching
+// rows, best match first.
+func Search(db *sqlx.DB, table, query string) (*sqlx.Rows, error) {
+	stmt := fmt.Sprintf("SELECT * FROM %[1]s WHERE %[1]s MATCH ? ORDER BY rank", quoteIdent(table))
+	rows, err := db.Queryx(stmt, query)
+	if err != nil {
+		return nil, fmt.Errorf("search %s: %w", table, err)
+	}
+
+	return rows, nil
+}
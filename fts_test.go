@@ -0,0 +1,66 @@
+package sqlite_base
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCreateFTS5Table(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE docs (id INTEGER PRIMARY KEY, title TEXT, body TEXT)")
+
+	supported, err := FTS5Supported(db)
+	if err != nil {
+		t.Fatalf("detect fts5 failed: %v", err)
+	}
+	if !supported {
+		err := CreateFTS5Table(db, "docs_fts", []string{"title", "body"}, FTS5Options{})
+		if !errors.Is(err, ErrFTS5Unsupported) {
+			t.Fatalf("expected ErrFTS5Unsupported, got %v", err)
+		}
+		t.Skip("fts5 not compiled in; run with -tags sqlite_fts5")
+	}
+
+	err = CreateFTS5Table(db, "docs_fts", []string{"title", "body"}, FTS5Options{
+		Tokenize:     "porter unicode61",
+		Content:      "docs",
+		ContentRowid: "id",
+	})
+	if err != nil {
+		t.Fatalf("create fts5 table failed: %v", err)
+	}
+	db.MustExec("INSERT INTO docs (title, body) VALUES ('SQLite', 'running full text searches'), ('Go', 'concurrency patterns')")
+	db.MustExec("INSERT INTO docs_fts (docs_fts) VALUES ('rebuild')")
+
+	schema := Schema{
+		FTS5Tables: []string{"docs_fts"},
+		Columns:    map[string]map[string]string{"docs_fts": {"title": "", "body": ""}},
+	}
+	if err := ValidateSchema(db, schema); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	if err := ValidateSchema(db, Schema{FTS5Tables: []string{"docs"}}); err == nil {
+		t.Fatal("expected plain table to fail fts5 validation")
+	}
+
+	rows, err := Search(db, "docs_fts", "search")
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	defer rows.Close()
+	var titles []string
+	for rows.Next() {
+		var title, body string
+		if err := rows.Scan(&title, &body); err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+		titles = append(titles, title)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows failed: %v", err)
+	}
+	if len(titles) != 1 || titles[0] != "SQLite" {
+		t.Fatalf("expected porter stemming to match SQLite, got %v", titles)
+	}
+}
@@ -0,0 +1,56 @@
+package sqlite_base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// HealthCheck pings the database and runs a trivial statement so that a
+// readiness probe fails when connections open but cannot execute queries.
+func HealthCheck(ctx context.Context, db *sqlx.DB) error {
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping sqlite database: %w", err)
+	}
+
+	var one int
+	if err := db.GetContext(ctx, &one, "SELECT 1"); err != nil {
+		return fmt.Errorf("execute health query: %w", err)
+	}
+
+	return nil
+}
+
+// Warmup opens db.SetMaxOpenConns connections at once, so that each one is
+// created and configured (pragmas, attachments, functions, ConnectHook) before
+// the first request, and returns them to the pool. database/sql keeps at most
+// SetMaxIdleConns idle connections, two by default, and closes the rest, so
+// raise it to the same value for the warm connections to stay open.
+func Warmup(db *sqlx.DB) error {
+	n := db.Stats().MaxOpenConnections
+	if n <= 0 {
+		return errors.New("warmup needs a connection limit; call SetMaxOpenConns first")
+	}
+
+	ctx := context.Background()
+	conns := make([]*sqlx.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+	for i := 0; i < n; i++ {
+		conn, err := db.Connx(ctx)
+		if err != nil {
+			return fmt.Errorf("open connection %d of %d: %w", i+1, n, err)
+		}
+		conns = append(conns, conn)
+		if err := conn.PingContext(ctx); err != nil {
+			return fmt.Errorf("ping connection %d of %d: %w", i+1, n, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package sqlite_base
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t)
+
+	if err := HealthCheck(context.Background(), db); err != nil {
+		t.Fatalf("health check failed: %v", err)
+	}
+
+	_ = db.Close()
+	err := HealthCheck(context.Background(), db)
+	if err == nil || !strings.Contains(err.Error(), "ping sqlite database") {
+		t.Fatalf("expected ping error on closed database, got: %v", err)
+	}
+}
+
+func TestHealthCheck_CancelledContext(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := HealthCheck(ctx, db); err == nil {
+		t.Fatal("expected error for cancelled context")
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	t.Parallel()
+
+	var hooks atomic.Int32
+	db, err := Open(Config{
+		Path: filepath.Join(t.TempDir(), "app.sqlite"),
+		ConnectHook: func(*sqlite3.SQLiteConn) error {
+			hooks.Add(1)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if err := Warmup(db); err == nil {
+		t.Fatal("expected warmup without a connection limit to fail")
+	}
+
+	db.SetMaxOpenConns(4)
+	db.SetMaxIdleConns(4)
+	before := hooks.Load()
+	if err := Warmup(db); err != nil {
+		t.Fatalf("warmup failed: %v", err)
+	}
+
+	stats := db.Stats()
+	if stats.OpenConnections != 4 || stats.Idle != 4 {
+		t.Fatalf("expected 4 idle connections, got %d open, %d idle", stats.OpenConnections, stats.Idle)
+	}
+	if opened := hooks.Load() - before; opened < 3 {
+		t.Fatalf("expected the new connections to run the connect hook, got %d", opened)
+	}
+}
@@ -0,0 +1,43 @@
+package sqlite_base
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// initLocks serializes schema initialization per database file within the
+// process, keyed by absolute path.
+var initLocks sync.Map
+
+// lockInit takes the in-process lock for path and, when crossProcess is set
+// and path names a plain file, an advisory file lock on path + ".init.lock"
+// as well. The returned function releases both.
+func lockInit(path string, crossProcess bool) (func(), error) {
+	key := path
+	fileBacked := !strings.HasPrefix(path, "file:") && path != ":memory:"
+	if fileBacked {
+		if abs, err := filepath.Abs(path); err == nil {
+			key = abs
+		}
+	}
+
+	value, _ := initLocks.LoadOrStore(key, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+
+	if !crossProcess || !fileBacked {
+		return mu.Unlock, nil
+	}
+
+	unlockFile, err := lockFile(key + ".init.lock")
+	if err != nil {
+		mu.Unlock()
+		return nil, err
+	}
+
+	return func() {
+		unlockFile()
+		mu.Unlock()
+	}, nil
+}
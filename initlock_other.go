@@ -0,0 +1,9 @@
+//go:build !unix
+
+package sqlite_base
+
+import "errors"
+
+func lockFile(string) (func(), error) {
+	return nil, errors.New("cross-process init lock is not supported on this platform")
+}
@@ -0,0 +1,43 @@
+package sqlite_base
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOpen_ConcurrentFirstInitialization(t *testing.T) {
+	t.Parallel()
+
+	for _, crossProcess := range []bool{false, true} {
+		config := Config{
+			Path:             filepath.Join(t.TempDir(), "app.sqlite"),
+			DSN:              DSN{BusyTimeout: 5 * time.Second},
+			MigrationDir:     "examples/migrations",
+			MigrationFS:      embedMigrations,
+			CrossProcessLock: crossProcess,
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				db, err := Open(config)
+				if err != nil {
+					errs <- err
+					return
+				}
+				_ = db.Close()
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Fatalf("concurrent open (cross-process lock %v) failed: %v", crossProcess, err)
+		}
+	}
+}
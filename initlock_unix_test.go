@@ -0,0 +1,48 @@
+//go:build unix
+
+package sqlite_base
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpen_CrossProcessLockWaitsForOtherHolder(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "app.sqlite")
+	// flock locks belong to the open file description, so a lock taken
+	// directly through lockFile conflicts with Open the same way another
+	// process holding it would, without the in-process mutex.
+	unlock, err := lockFile(path + ".init.lock")
+	if err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+
+	opened := make(chan error, 1)
+	go func() {
+		db, err := Open(Config{Path: path, MigrationDir: "examples/migrations", MigrationFS: embedMigrations, CrossProcessLock: true})
+		if err == nil {
+			_ = db.Close()
+		}
+		opened <- err
+	}()
+
+	select {
+	case err := <-opened:
+		unlock()
+		t.Fatalf("expected Open to wait for the init lock, returned %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case err := <-opened:
+		if err != nil {
+			t.Fatalf("open after unlock failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Open did not proceed after the init lock was released")
+	}
+}
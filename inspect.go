@@ -0,0 +1,250 @@
+package sqlite_base
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ListTables returns the user tables in the main schema, sorted by name and
+// excluding SQLite's internal sqlite_* tables.
+func ListTables(db *sqlx.DB) ([]string, error) {
+	var tables []string
+	err := db.Select(&tables, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite\_%' ESCAPE '\' ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+
+	return tables, nil
+}
+
+// UnreachableTables returns the tables that cannot be reached from roots by
+// following foreign keys in either direction. The result is sorted by name.
+func UnreachableTables(db *sqlx.DB, roots []string) ([]string, error) {
+	tables, err := ListTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(tables))
+	links := make(map[string][]string, len(tables))
+	for _, table := range tables {
+		names[strings.ToLower(table)] = table
+	}
+	for _, table := range tables {
+		var parents []string
+		if err := db.Select(&parents, `SELECT "table" FROM pragma_foreign_key_list(?)`, table); err != nil {
+			return nil, fmt.Errorf("query foreign keys for %s: %w", table, err)
+		}
+		for _, parent := range parents {
+			parent, ok := names[strings.ToLower(parent)]
+			if !ok {
+				continue
+			}
+			links[table] = append(links[table], parent)
+			links[parent] = append(links[parent], table)
+		}
+	}
+
+	visited := make(map[string]bool, len(tables))
+	queue := make([]string, 0, len(tables))
+	for _, root := range roots {
+		table, ok := names[strings.ToLower(root)]
+		if !ok {
+			return nil, fmt.Errorf("root table %s does not exist", root)
+		}
+		if !visited[table] {
+			visited[table] = true
+			queue = append(queue, table)
+		}
+	}
+	for len(queue) > 0 {
+		table := queue[0]
+		queue = queue[1:]
+		for _, next := range links[table] {
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	unreachable := []string{}
+	for _, table := range tables {
+		if !visited[table] {
+			unreachable = append(unreachable, table)
+		}
+	}
+	sort.Strings(unreachable)
+
+	return unreachable, nil
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// DerivedSchemaVersion returns a CRC32 checksum of the normalized schema SQL,
+// suitable for comparing against a value compiled into the application.
+func DerivedSchemaVersion(db *sqlx.DB) (uint32, error) {
+	stmts, err := normalizedSchema(db)
+	if err != nil {
+		return 0, err
+	}
+
+	return crc32.ChecksumIEEE([]byte(strings.Join(stmts, ";\n"))), nil
+}
+
+// SchemaFingerprint returns the hex SHA-256 digest of the same normalized
+// schema SQL as DerivedSchemaVersion, for comparing against an expected
+// fingerprint at startup where a CRC32 collision is not acceptable.
+func SchemaFingerprint(db *sqlx.DB) (string, error) {
+	stmts, err := normalizedSchema(db)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(strings.Join(stmts, ";\n")))
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// normalizedSchema returns the stored SQL of every user object with runs of
+// whitespace collapsed, ordered by type and name.
+func normalizedSchema(db *sqlx.DB) ([]string, error) {
+	var stmts []string
+	err := db.Select(&stmts, `SELECT sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite\_%' ESCAPE '\' ORDER BY type, name`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema: %w", err)
+	}
+	for i, stmt := range stmts {
+		stmts[i] = normalizeSQL(stmt)
+	}
+
+	return stmts, nil
+}
+
+func tableSQL(db *sqlx.DB, table string) (string, error) {
+	var stmt string
+	err := db.Get(&stmt, `SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ? COLLATE NOCASE`, table)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("table %s does not exist", table)
+		}
+		return "", fmt.Errorf("query table %s: %w", table, err)
+	}
+
+	return stmt, nil
+}
+
+// hasTableOption reports whether a CREATE TABLE statement ends with the given
+// table option, such as "WITHOUT ROWID" or "STRICT".
+func hasTableOption(createSQL, option string) bool {
+	end := strings.LastIndex(createSQL, ")")
+	if end < 0 {
+		return false
+	}
+	for _, opt := range strings.Split(createSQL[end+1:], ",") {
+		if strings.EqualFold(strings.Join(strings.Fields(opt), " "), option) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateRowidAlias reports whether table's primary key is an alias for the
+// rowid: a single column declared exactly INTEGER PRIMARY KEY (not INT, and
+// not the INTEGER PRIMARY KEY DESC column constraint) on a rowid table.
+func ValidateRowidAlias(db *sqlx.DB, table string) (bool, error) {
+	createSQL, err := tableSQL(db, table)
+	if err != nil {
+		return false, err
+	}
+	if hasTableOption(createSQL, "WITHOUT ROWID") {
+		return false, nil
+	}
+
+	columns, err := GetTableColumns(db, table)
+	if err != nil {
+		return false, err
+	}
+	var pk []ColumnInfo
+	for _, column := range columns {
+		if column.PrimaryKey > 0 {
+			pk = append(pk, column)
+		}
+	}
+	if len(pk) != 1 || !strings.EqualFold(pk[0].Type, "INTEGER") {
+		return false, nil
+	}
+
+	desc := regexp.MustCompile(`(?i)["\x60\[]?` + regexp.QuoteMeta(pk[0].Name) + `["\x60\]]?\s+INTEGER\s+PRIMARY\s+KEY\s+DESC\b`)
+
+	return !desc.MatchString(createSQL), nil
+}
+
+type triggerInfo struct {
+	Table string `db:"tbl_name"`
+	SQL   string `db:"sql"`
+}
+
+var afterUpdatePattern = regexp.MustCompile(`(?is)\bAFTER\s+UPDATE\b`)
+
+// TablesMissingUpdateTrigger returns the tables that have column but no AFTER
+// UPDATE trigger whose SQL mentions it, such as one maintaining updated_at.
+// The trigger SQL is matched textually, so the result is advisory.
+func TablesMissingUpdateTrigger(db *sqlx.DB, column string) ([]string, error) {
+	tables, err := ListTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var triggers []triggerInfo
+	if err := db.Select(&triggers, `SELECT tbl_name, sql FROM sqlite_master WHERE type = 'trigger'`); err != nil {
+		return nil, fmt.Errorf("list triggers: %w", err)
+	}
+	mentions := regexp.MustCompile(`(?i)(^|[^\w])` + regexp.QuoteMeta(column) + `($|[^\w])`)
+	covered := make(map[string]bool)
+	for _, trigger := range triggers {
+		if afterUpdatePattern.MatchString(trigger.SQL) && mentions.MatchString(trigger.SQL) {
+			covered[strings.ToLower(trigger.Table)] = true
+		}
+	}
+
+	missing := []string{}
+	for _, table := range tables {
+		var count int
+		err := db.Get(&count, `SELECT COUNT(1) FROM pragma_table_info(?) WHERE name = ? COLLATE NOCASE`, table, column)
+		if err != nil {
+			return nil, fmt.Errorf("query table info for %s: %w", table, err)
+		}
+		if count > 0 && !covered[strings.ToLower(table)] {
+			missing = append(missing, table)
+		}
+	}
+
+	return missing, nil
+}
+
+// CheckReferentialCounts counts rows of childTable whose non-NULL childFKCol
+// value has no matching parentPKCol in parentTable. It does not rely on
+// foreign key enforcement, so it also finds orphans written while it was off.
+func CheckReferentialCounts(db *sqlx.DB, childTable, childFKCol, parentTable, parentPKCol string) (orphans int64, err error) {
+	query := fmt.Sprintf(
+		`SELECT COUNT(*) FROM %[1]s AS c LEFT JOIN %[3]s AS p ON c.%[2]s = p.%[4]s WHERE c.%[2]s IS NOT NULL AND p.%[4]s IS NULL`,
+		quoteIdent(childTable), quoteIdent(childFKCol), quoteIdent(parentTable), quoteIdent(parentPKCol),
+	)
+	if err := db.Get(&orphans, query); err != nil {
+		return 0, fmt.Errorf("count orphans in %s.%s: %w", childTable, childFKCol, err)
+	}
+
+	return orphans, nil
+}
@@ -0,0 +1,175 @@
+package sqlite_base
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUnreachableTables_ReportsDisconnectedTables(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER REFERENCES customers (id))",
+		"CREATE TABLE order_items (id INTEGER PRIMARY KEY, order_id INTEGER REFERENCES orders (id))",
+		"CREATE TABLE audit_log (id INTEGER PRIMARY KEY, message TEXT)",
+	)
+
+	unreachable, err := UnreachableTables(db, []string{"customers"})
+	if err != nil {
+		t.Fatalf("unreachable tables failed: %v", err)
+	}
+	if want := []string{"audit_log"}; !reflect.DeepEqual(unreachable, want) {
+		t.Fatalf("expected %v, got %v", want, unreachable)
+	}
+
+	if _, err := UnreachableTables(db, []string{"missing"}); err == nil {
+		t.Fatal("expected error for missing root table")
+	}
+}
+
+func TestDerivedSchemaVersion_StableAndSensitiveToChanges(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "app.sqlite")
+	db, err := Open(Config{Path: dbPath})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	db.MustExec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+
+	before, err := DerivedSchemaVersion(db)
+	if err != nil {
+		t.Fatalf("derived schema version failed: %v", err)
+	}
+	_ = db.Close()
+
+	db, err = Open(Config{Path: dbPath})
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	reopened, err := DerivedSchemaVersion(db)
+	if err != nil {
+		t.Fatalf("derived schema version failed: %v", err)
+	}
+	if reopened != before {
+		t.Fatalf("expected stable version across reopen, got %d and %d", before, reopened)
+	}
+
+	db.MustExec("ALTER TABLE users ADD COLUMN email TEXT")
+	after, err := DerivedSchemaVersion(db)
+	if err != nil {
+		t.Fatalf("derived schema version failed: %v", err)
+	}
+	if after == before {
+		t.Fatal("expected version to change after adding a column")
+	}
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	t.Parallel()
+
+	a := newTestDB(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	b := newTestDB(t, "CREATE TABLE users (id  INTEGER\n\tPRIMARY KEY, name TEXT\n\tNOT NULL)")
+
+	first, err := SchemaFingerprint(a)
+	if err != nil {
+		t.Fatalf("fingerprint failed: %v", err)
+	}
+	if len(first) != 64 {
+		t.Fatalf("expected hex sha-256 digest, got %q", first)
+	}
+	second, err := SchemaFingerprint(b)
+	if err != nil {
+		t.Fatalf("fingerprint failed: %v", err)
+	}
+	if first != second {
+		t.Fatalf("expected whitespace-insensitive fingerprint, got %s and %s", first, second)
+	}
+
+	b.MustExec("CREATE INDEX idx_users_name ON users (name)")
+	changed, err := SchemaFingerprint(b)
+	if err != nil {
+		t.Fatalf("fingerprint failed: %v", err)
+	}
+	if changed == first {
+		t.Fatal("expected fingerprint to change after adding an index")
+	}
+}
+
+func TestValidateRowidAlias(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE alias (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE not_alias (id INT PRIMARY KEY, name TEXT)",
+		"CREATE TABLE desc_key (id INTEGER PRIMARY KEY DESC, name TEXT)",
+		"CREATE TABLE table_constraint (id INTEGER, name TEXT, PRIMARY KEY (id DESC))",
+		"CREATE TABLE no_rowid (id INTEGER PRIMARY KEY, name TEXT) WITHOUT ROWID",
+	)
+
+	tests := map[string]bool{
+		"alias":            true,
+		"not_alias":        false,
+		"desc_key":         false,
+		"table_constraint": true,
+		"no_rowid":         false,
+	}
+	for table, want := range tests {
+		got, err := ValidateRowidAlias(db, table)
+		if err != nil {
+			t.Fatalf("%s: validate rowid alias failed: %v", table, err)
+		}
+		if got != want {
+			t.Fatalf("%s: expected %t, got %t", table, want, got)
+		}
+	}
+
+	if _, err := ValidateRowidAlias(db, "missing"); err == nil {
+		t.Fatal("expected error for missing table")
+	}
+}
+
+func TestTablesMissingUpdateTrigger(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, updated_at DATETIME)",
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT, updated_at DATETIME)",
+		"CREATE TABLE tags (id INTEGER PRIMARY KEY, label TEXT)",
+		`CREATE TRIGGER users_touch AFTER UPDATE ON users
+		 BEGIN
+		   UPDATE users SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+		 END`,
+	)
+
+	missing, err := TablesMissingUpdateTrigger(db, "updated_at")
+	if err != nil {
+		t.Fatalf("tables missing update trigger failed: %v", err)
+	}
+	if want := []string{"posts"}; !reflect.DeepEqual(missing, want) {
+		t.Fatalf("expected %v, got %v", want, missing)
+	}
+}
+
+func TestCheckReferentialCounts(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER REFERENCES customers (id))",
+		"INSERT INTO customers (id) VALUES (1), (2)",
+		"INSERT INTO orders (customer_id) VALUES (1), (2), (NULL), (99)",
+	)
+
+	orphans, err := CheckReferentialCounts(db, "orders", "customer_id", "customers", "id")
+	if err != nil {
+		t.Fatalf("check referential counts failed: %v", err)
+	}
+	if orphans != 1 {
+		t.Fatalf("expected 1 orphan, got %d", orphans)
+	}
+}
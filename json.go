@@ -0,0 +1,94 @@
+package sqlite_base
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// InsertJSON marshals v and inserts it into column of a new row of table
+// whose primary key is id. The value goes through SQLite's json() function,
+// so it is stored as minified JSON text that the json_* functions accept. A
+// nil id leaves the key to SQLite, e.g. an INTEGER PRIMARY KEY rowid alias.
+func InsertJSON(db *sqlx.DB, table, column string, id any, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal %s.%s: %w", table, column, err)
+	}
+
+	if id == nil {
+		stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES (json(?))", quoteIdent(table), quoteIdent(column))
+		if _, err := db.Exec(stmt, string(data)); err != nil {
+			return fmt.Errorf("insert json into %s.%s: %w", table, column, err)
+		}
+		return nil
+	}
+
+	columns, err := GetTableColumns(db, table)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("table %s does not exist", table)
+	}
+	var pk []string
+	for _, c := range columns {
+		if c.PrimaryKey > 0 {
+			pk = append(pk, c.Name)
+		}
+	}
+	if len(pk) != 1 {
+		return fmt.Errorf("table %s needs a single-column primary key to insert by id, has %d", table, len(pk))
+	}
+
+	stmt := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (?, json(?))", quoteIdent(table), quoteIdent(pk[0]), quoteIdent(column))
+	if _, err := db.Exec(stmt, id, string(data)); err != nil {
+		return fmt.Errorf("insert json into %s.%s: %w", table, column, err)
+	}
+
+	return nil
+}
+
+// QueryJSON runs query, which must return a single JSON text column, and
+// unmarshals each row into a T. NULL is treated as JSON null and yields the
+// zero value of T.
+func QueryJSON[T any](db *sqlx.DB, query string, args ...any) ([]T, error) {
+	var raw []sql.NullString
+	if err := db.Select(&raw, query, args...); err != nil {
+		return nil, fmt.Errorf("query json: %w", err)
+	}
+
+	values := make([]T, len(raw))
+	for i, r := range raw {
+		if !r.Valid {
+			continue
+		}
+		if err := json.Unmarshal([]byte(r.String), &values[i]); err != nil {
+			return nil, fmt.Errorf("unmarshal json row %d: %w", i+1, err)
+		}
+	}
+
+	return values, nil
+}
+
+// ValidateJSONColumn checks that every non-NULL value of table.column is
+// well-formed JSON according to json_valid.
+func ValidateJSONColumn(db *sqlx.DB, table, column string) error {
+	if strings.TrimSpace(column) == "" {
+		return fmt.Errorf("table %s: column name is required", table)
+	}
+
+	var invalid int64
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %[1]s WHERE %[2]s IS NOT NULL AND NOT json_valid(%[2]s)", quoteIdent(table), quoteIdent(column))
+	if err := db.Get(&invalid, query); err != nil {
+		return fmt.Errorf("validate json in %s.%s: %w", table, column, err)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("table %s: column %s has %d rows with invalid JSON", table, column, invalid)
+	}
+
+	return nil
+}
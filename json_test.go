@@ -0,0 +1,73 @@
+package sqlite_base
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type jsonSettings struct {
+	Theme string   `json:"theme"`
+	Tags  []string `json:"tags"`
+}
+
+func TestInsertAndQueryJSON(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, settings TEXT)",
+		"CREATE TABLE tags (name TEXT PRIMARY KEY, meta TEXT)",
+	)
+
+	if err := InsertJSON(db, "users", "settings", 7, jsonSettings{Theme: "dark", Tags: []string{"a", "b"}}); err != nil {
+		t.Fatalf("insert json failed: %v", err)
+	}
+	if err := InsertJSON(db, "users", "settings", nil, jsonSettings{Theme: "light"}); err != nil {
+		t.Fatalf("insert json without id failed: %v", err)
+	}
+	if err := InsertJSON(db, "tags", "meta", "go", map[string]int{"stars": 5}); err != nil {
+		t.Fatalf("insert json with text key failed: %v", err)
+	}
+	db.MustExec("INSERT INTO users (id, settings) VALUES (20, NULL)")
+
+	settings, err := QueryJSON[jsonSettings](db, "SELECT settings FROM users ORDER BY id")
+	if err != nil {
+		t.Fatalf("query json failed: %v", err)
+	}
+	want := []jsonSettings{{Theme: "dark", Tags: []string{"a", "b"}}, {Theme: "light"}, {}}
+	if !reflect.DeepEqual(settings, want) {
+		t.Fatalf("unexpected settings: %+v", settings)
+	}
+
+	var theme string
+	if err := db.Get(&theme, "SELECT settings ->> '$.theme' FROM users WHERE id = 7"); err != nil {
+		t.Fatalf("json path query failed: %v", err)
+	}
+	if theme != "dark" {
+		t.Fatalf("expected dark, got %q", theme)
+	}
+
+	stars, err := QueryJSON[map[string]int](db, "SELECT meta FROM tags WHERE name = ?", "go")
+	if err != nil || len(stars) != 1 || stars[0]["stars"] != 5 {
+		t.Fatalf("unexpected meta: %v, %v", stars, err)
+	}
+}
+
+func TestValidateJSONColumn(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE events (id INTEGER PRIMARY KEY, payload TEXT)",
+		`INSERT INTO events (payload) VALUES ('{"a": 1}'), (NULL), ('[1, 2]')`,
+	)
+
+	if err := ValidateJSONColumn(db, "events", "payload"); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+
+	db.MustExec("INSERT INTO events (payload) VALUES ('{broken')")
+	err := ValidateJSONColumn(db, "events", "payload")
+	if err == nil || !strings.Contains(err.Error(), "1 rows with invalid JSON") {
+		t.Fatalf("expected invalid json error, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package sqlite_base
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Logger receives the package's diagnostic messages, including the progress
+// output of goose migrations. Config.Logger defaults to a logger that
+// discards everything.
+type Logger interface {
+	Debugf(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...any) {}
+func (nopLogger) Warnf(string, ...any)  {}
+func (nopLogger) Errorf(string, ...any) {}
+
+// SlogLogger returns a Logger that writes to l, or to slog.Default() when l
+// is nil.
+func SlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+
+	return slogLogger{logger: l}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) Debugf(format string, args ...any) { l.log(slog.LevelDebug, format, args...) }
+func (l slogLogger) Warnf(format string, args ...any)  { l.log(slog.LevelWarn, format, args...) }
+func (l slogLogger) Errorf(format string, args ...any) { l.log(slog.LevelError, format, args...) }
+
+func (l slogLogger) log(level slog.Level, format string, args ...any) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	l.logger.Log(ctx, level, fmt.Sprintf(format, args...), slog.String("component", "sqlite-base"))
+}
+
+func loggerOrNop(logger Logger) Logger {
+	if logger == nil {
+		return nopLogger{}
+	}
+
+	return logger
+}
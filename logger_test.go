@@ -0,0 +1,92 @@
+package sqlite_base
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) record(level, format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, level+" "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Debugf(format string, args ...any) { l.record("debug", format, args...) }
+func (l *recordingLogger) Warnf(format string, args ...any)  { l.record("warn", format, args...) }
+func (l *recordingLogger) Errorf(format string, args ...any) { l.record("error", format, args...) }
+
+func (l *recordingLogger) contains(substr string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, message := range l.messages {
+		if strings.Contains(message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOpen_RoutesMigrationLogsToLogger(t *testing.T) {
+	t.Parallel()
+
+	logger := &recordingLogger{}
+
+	db, err := Open(Config{
+		Path:         filepath.Join(t.TempDir(), "app.sqlite"),
+		MigrationDir: "examples/migrations",
+		MigrationFS:  embedMigrations,
+		Logger:       logger,
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if !logger.contains("debug OK   00001_init.sql") {
+		t.Fatalf("expected goose output in logger, got %v", logger.messages)
+	}
+}
+
+func TestApplyMigrationsFS_KeepsStandardLogOutput(t *testing.T) {
+	var buf bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&buf)
+	defer log.SetOutput(previous)
+
+	db := newTestDB(t)
+	if err := ApplyMigrationsFS(db, embedMigrations, "examples/migrations"); err != nil {
+		t.Fatalf("apply migrations failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "OK   00001_init.sql") || !strings.Contains(out, "migrated database to version: 2") {
+		t.Fatalf("expected goose output on the standard logger, got %q", out)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := SlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	logger.Debugf("hidden %d", 1)
+	logger.Warnf("migration %s is slow", "00001_init.sql")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Fatalf("expected debug message to be filtered, got %q", out)
+	}
+	if !strings.Contains(out, `level=WARN msg="migration 00001_init.sql is slow" component=sqlite-base`) {
+		t.Fatalf("unexpected slog output: %q", out)
+	}
+}
@@ -0,0 +1,83 @@
+package sqlite_base
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CheckIntegrity runs PRAGMA integrity_check and returns an error listing the
+// reported problems unless SQLite answers with a single "ok" row.
+func CheckIntegrity(db *sqlx.DB) error {
+	return runIntegrityPragma(db, "integrity_check")
+}
+
+// QuickCheck runs PRAGMA quick_check, a faster variant of CheckIntegrity that
+// skips index content verification.
+func QuickCheck(db *sqlx.DB) error {
+	return runIntegrityPragma(db, "quick_check")
+}
+
+func runIntegrityPragma(db *sqlx.DB, pragma string) error {
+	var results []string
+	if err := db.Select(&results, "PRAGMA "+pragma); err != nil {
+		return fmt.Errorf("run %s: %w", pragma, err)
+	}
+
+	if len(results) == 1 && results[0] == "ok" {
+		return nil
+	}
+
+	return fmt.Errorf("%s failed: %s", pragma, strings.Join(results, "; "))
+}
+
+// Analyze runs ANALYZE on the given tables, or on the whole database when no
+// table is named, refreshing the statistics the query planner relies on.
+func Analyze(db *sqlx.DB, tables ...string) error {
+	if len(tables) == 0 {
+		if _, err := db.Exec("ANALYZE"); err != nil {
+			return fmt.Errorf("analyze database: %w", err)
+		}
+		return nil
+	}
+
+	for _, table := range tables {
+		if _, err := db.Exec("ANALYZE " + quoteIdent(table)); err != nil {
+			return fmt.Errorf("analyze %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// Optimize runs PRAGMA optimize, which re-analyzes tables whose statistics
+// are likely stale. SQLite recommends running it periodically and before
+// closing long-lived connections; it only considers the queries run on the
+// connection that executes it, so with a pool it works best when called
+// regularly rather than once.
+func Optimize(db *sqlx.DB) error {
+	if _, err := db.Exec("PRAGMA optimize"); err != nil {
+		return fmt.Errorf("optimize database: %w", err)
+	}
+
+	return nil
+}
+
+// Reindex rebuilds every index when target is empty, or the indexes of the
+// named index, table or collation sequence otherwise. An unknown target is
+// reported with SQLite's own error.
+func Reindex(db *sqlx.DB, target string) error {
+	if strings.TrimSpace(target) == "" {
+		if _, err := db.Exec("REINDEX"); err != nil {
+			return fmt.Errorf("reindex database: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := db.Exec("REINDEX " + quoteIdent(target)); err != nil {
+		return fmt.Errorf("reindex %s: %w", target, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package sqlite_base
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckIntegrity_HealthyDatabase(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL)",
+		"CREATE UNIQUE INDEX idx_users_email ON users (email)",
+		"INSERT INTO users (email) VALUES ('a@example.com'), ('b@example.com')",
+	)
+
+	if err := CheckIntegrity(db); err != nil {
+		t.Fatalf("integrity check failed: %v", err)
+	}
+	if err := QuickCheck(db); err != nil {
+		t.Fatalf("quick check failed: %v", err)
+	}
+}
+
+func TestCheckIntegrity_CorruptDatabase(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "app.sqlite")
+	db, err := Open(Config{Path: dbPath})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	db.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY, payload TEXT)")
+	for i := 0; i < 200; i++ {
+		db.MustExec("INSERT INTO items (payload) VALUES (?)", strings.Repeat("x", 200))
+	}
+	_ = db.Close()
+
+	raw, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatalf("read database failed: %v", err)
+	}
+	for i := 4096 + 100; i < len(raw) && i < 4096*3; i++ {
+		raw[i] = 0xff
+	}
+	if err := os.WriteFile(dbPath, raw, 0o600); err != nil {
+		t.Fatalf("write database failed: %v", err)
+	}
+
+	db, err = Open(Config{Path: dbPath})
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if err := CheckIntegrity(db); err == nil {
+		t.Fatal("expected integrity check to report corruption")
+	}
+}
+
+func TestAnalyzeAndOptimize(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL)",
+		"CREATE INDEX idx_users_email ON users (email)",
+		"INSERT INTO users (email) VALUES ('a@example.com'), ('b@example.com')",
+	)
+
+	if err := Analyze(db, "users"); err != nil {
+		t.Fatalf("analyze table failed: %v", err)
+	}
+	var stats int
+	if err := db.Get(&stats, "SELECT COUNT(*) FROM sqlite_stat1 WHERE tbl = 'users'"); err != nil {
+		t.Fatalf("read sqlite_stat1 failed: %v", err)
+	}
+	if stats == 0 {
+		t.Fatal("expected ANALYZE to record statistics for users")
+	}
+
+	if err := Analyze(db); err != nil {
+		t.Fatalf("analyze database failed: %v", err)
+	}
+	if err := Analyze(db, "missing"); err == nil {
+		t.Fatal("expected analyzing a missing table to fail")
+	}
+	if err := Optimize(db); err != nil {
+		t.Fatalf("optimize failed: %v", err)
+	}
+}
+
+func TestReindex(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL)",
+		"CREATE INDEX idx_users_email ON users (email)",
+	)
+
+	for _, target := range []string{"", "users", "idx_users_email", "NOCASE"} {
+		if err := Reindex(db, target); err != nil {
+			t.Fatalf("reindex %q failed: %v", target, err)
+		}
+	}
+	if err := Reindex(db, "missing"); err == nil || !strings.Contains(err.Error(), "unable to identify the object to be reindexed") {
+		t.Fatalf("expected SQLite's unknown object error, got %v", err)
+	}
+}
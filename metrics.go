@@ -0,0 +1,77 @@
+package sqlite_base
+
+import (
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type StatementKind string
+
+const (
+	StatementExec  StatementKind = "exec"
+	StatementQuery StatementKind = "query"
+)
+
+// MetricsCollector observes every statement run directly on a connection
+// opened with Config.Metrics. Query durations cover preparing the statement
+// and producing the first row, not iterating the remaining rows. Statements
+// prepared explicitly (Prepare, Preparex) are observed on every execution.
+type MetricsCollector interface {
+	ObserveStatement(kind StatementKind, query string, duration time.Duration, err error)
+}
+
+// Statistics combines the pool statistics of database/sql, useful for spotting
+// pool saturation through WaitCount and WaitDuration, with statement
+// counters that are only populated when the database was opened with
+// Config.Metrics.
+type Statistics struct {
+	sql.DBStats
+	Execs         int64
+	Queries       int64
+	Errors        int64
+	ExecDuration  time.Duration
+	QueryDuration time.Duration
+}
+
+func Stats(db *sqlx.DB) Statistics {
+	stats := Statistics{DBStats: db.Stats()}
+
+	c, ok := db.Driver().(*connector)
+	if !ok || c.metrics == nil {
+		return stats
+	}
+	stats.Execs = c.metrics.execs.Load()
+	stats.Queries = c.metrics.queries.Load()
+	stats.Errors = c.metrics.errors.Load()
+	stats.ExecDuration = time.Duration(c.metrics.execNanos.Load())
+	stats.QueryDuration = time.Duration(c.metrics.queryNanos.Load())
+
+	return stats
+}
+
+type statementMetrics struct {
+	collector  MetricsCollector
+	execs      atomic.Int64
+	queries    atomic.Int64
+	errors     atomic.Int64
+	execNanos  atomic.Int64
+	queryNanos atomic.Int64
+}
+
+func (m *statementMetrics) observe(kind StatementKind, query string, start time.Time, err error) {
+	duration := time.Since(start)
+	if kind == StatementExec {
+		m.execs.Add(1)
+		m.execNanos.Add(int64(duration))
+	} else {
+		m.queries.Add(1)
+		m.queryNanos.Add(int64(duration))
+	}
+	if err != nil {
+		m.errors.Add(1)
+	}
+	m.collector.ObserveStatement(kind, query, duration, err)
+}
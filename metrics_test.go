@@ -0,0 +1,95 @@
+package sqlite_base
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingCollector struct {
+	mu    sync.Mutex
+	kinds []StatementKind
+	errs  int
+}
+
+func (c *recordingCollector) ObserveStatement(kind StatementKind, _ string, _ time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.kinds = append(c.kinds, kind)
+	if err != nil {
+		c.errs++
+	}
+}
+
+func TestStats_CountsStatements(t *testing.T) {
+	t.Parallel()
+
+	collector := &recordingCollector{}
+	db, err := Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite"), Metrics: collector})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	before := Stats(db)
+	db.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+	db.MustExec("INSERT INTO items DEFAULT VALUES")
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM items"); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO missing DEFAULT VALUES"); err == nil {
+		t.Fatal("expected insert into missing table to fail")
+	}
+
+	after := Stats(db)
+	if got := after.Execs - before.Execs; got != 3 {
+		t.Fatalf("expected 3 execs, got %d", got)
+	}
+	if got := after.Queries - before.Queries; got != 1 {
+		t.Fatalf("expected 1 query, got %d", got)
+	}
+	if got := after.Errors - before.Errors; got != 1 {
+		t.Fatalf("expected 1 error, got %d", got)
+	}
+	if after.ExecDuration <= 0 || after.OpenConnections == 0 {
+		t.Fatalf("expected durations and pool stats, got %+v", after)
+	}
+
+	stmt, err := db.Preparex("INSERT INTO items DEFAULT VALUES")
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	defer stmt.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := stmt.Exec(); err != nil {
+			t.Fatalf("exec prepared failed: %v", err)
+		}
+	}
+	if got := Stats(db).Execs - after.Execs; got != 2 {
+		t.Fatalf("expected 2 prepared execs, got %d", got)
+	}
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if collector.errs != 1 || len(collector.kinds) < 4 {
+		t.Fatalf("collector did not observe statements: %+v", collector.kinds)
+	}
+}
+
+func TestStats_WithoutMetrics(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite")})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	db.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+	stats := Stats(db)
+	if stats.Execs != 0 || stats.OpenConnections == 0 {
+		t.Fatalf("expected pool stats only, got %+v", stats)
+	}
+}
@@ -0,0 +1,77 @@
+package sqlite_base
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// sqliteKeywords is the keyword list from https://sqlite.org/lang_keywords.html.
+var sqliteKeywords = map[string]bool{}
+
+func init() {
+	for _, keyword := range strings.Fields(`
+		ABORT ACTION ADD AFTER ALL ALTER ALWAYS ANALYZE AND AS ASC ATTACH AUTOINCREMENT
+		BEFORE BEGIN BETWEEN BY CASCADE CASE CAST CHECK COLLATE COLUMN COMMIT CONFLICT
+		CONSTRAINT CREATE CROSS CURRENT CURRENT_DATE CURRENT_TIME CURRENT_TIMESTAMP
+		DATABASE DEFAULT DEFERRABLE DEFERRED DELETE DESC DETACH DISTINCT DO DROP EACH
+		ELSE END ESCAPE EXCEPT EXCLUDE EXCLUSIVE EXISTS EXPLAIN FAIL FILTER FIRST
+		FOLLOWING FOR FOREIGN FROM FULL GENERATED GLOB GROUP GROUPS HAVING IF IGNORE
+		IMMEDIATE IN INDEX INDEXED INITIALLY INNER INSERT INSTEAD INTERSECT INTO IS
+		ISNULL JOIN KEY LAST LEFT LIKE LIMIT MATCH MATERIALIZED NATURAL NO NOT NOTHING
+		NOTNULL NULL NULLS OF OFFSET ON OR ORDER OTHERS OUTER OVER PARTITION PLAN
+		PRAGMA PRECEDING PRIMARY QUERY RAISE RANGE RECURSIVE REFERENCES REGEXP REINDEX
+		RELEASE RENAME REPLACE RESTRICT RETURNING RIGHT ROLLBACK ROW ROWS SAVEPOINT
+		SELECT SET TABLE TEMP TEMPORARY THEN TIES TO TRANSACTION TRIGGER UNBOUNDED
+		UNION UNIQUE UPDATE USING VACUUM VALUES VIEW VIRTUAL WHEN WHERE WINDOW WITH
+		WITHOUT`) {
+		sqliteKeywords[keyword] = true
+	}
+}
+
+var rowidNames = map[string]bool{"ROWID": true, "OID": true, "_ROWID_": true}
+
+// CheckProblematicNames returns "table.column" entries whose column name is an
+// SQLite keyword, appears in the extra deny-list, or shadows one of the
+// implicit rowid names (rowid, oid, _rowid_) without being the table's
+// INTEGER PRIMARY KEY rowid alias.
+func CheckProblematicNames(db *sqlx.DB, extra ...string) ([]string, error) {
+	deny := make(map[string]bool, len(extra))
+	for _, name := range extra {
+		deny[strings.ToUpper(name)] = true
+	}
+
+	tables, err := ListTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []string{}
+	for _, table := range tables {
+		columns, err := GetTableColumns(db, table)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, column := range columns {
+			name := strings.ToUpper(column.Name)
+			switch {
+			case sqliteKeywords[name] || deny[name]:
+			case rowidNames[name]:
+				alias, err := ValidateRowidAlias(db, table)
+				if err != nil {
+					return nil, err
+				}
+				if alias && column.PrimaryKey > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("%s.%s", table, column.Name))
+		}
+	}
+
+	return problems, nil
+}
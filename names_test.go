@@ -0,0 +1,31 @@
+package sqlite_base
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckProblematicNames(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		`CREATE TABLE purchases (id INTEGER PRIMARY KEY, "order" INTEGER, oid TEXT, status TEXT)`,
+		"CREATE TABLE ledger (rowid INTEGER PRIMARY KEY, amount INTEGER)",
+	)
+
+	problems, err := CheckProblematicNames(db)
+	if err != nil {
+		t.Fatalf("check problematic names failed: %v", err)
+	}
+	if want := []string{"purchases.order", "purchases.oid"}; !reflect.DeepEqual(problems, want) {
+		t.Fatalf("expected %v, got %v", want, problems)
+	}
+
+	problems, err = CheckProblematicNames(db, "Status")
+	if err != nil {
+		t.Fatalf("check problematic names failed: %v", err)
+	}
+	if want := []string{"purchases.order", "purchases.oid", "purchases.status"}; !reflect.DeepEqual(problems, want) {
+		t.Fatalf("expected %v, got %v", want, problems)
+	}
+}
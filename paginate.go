@@ -0,0 +1,100 @@
+package sqlite_base
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Paginate returns page (counting from 1) of baseQuery's results, pageSize
+// rows at most, together with the total number of rows baseQuery returns.
+// pageSize is clamped to maxPageSize, so a page size taken from a request
+// cannot load an unbounded number of rows. baseQuery must not have its own
+// LIMIT and should have an ORDER BY so that pages are stable.
+func Paginate[T any](db *sqlx.DB, baseQuery string, args []any, page, pageSize, maxPageSize int) (items []T, total int64, err error) {
+	if page < 1 {
+		return nil, 0, fmt.Errorf("page must be at least 1, got %d", page)
+	}
+	if pageSize < 1 {
+		return nil, 0, fmt.Errorf("page size must be at least 1, got %d", pageSize)
+	}
+	if maxPageSize < 1 {
+		return nil, 0, fmt.Errorf("max page size must be at least 1, got %d", maxPageSize)
+	}
+	pageSize = min(pageSize, maxPageSize)
+
+	// The clauses added around baseQuery start on a new line so that a
+	// trailing -- comment in baseQuery cannot swallow them.
+	query := strings.TrimSuffix(strings.TrimSpace(baseQuery), ";")
+	if err := db.Get(&total, "SELECT COUNT(*) FROM (\n"+query+"\n)", args...); err != nil {
+		return nil, 0, fmt.Errorf("count rows: %w", err)
+	}
+
+	items = []T{}
+	pageArgs := append(append([]any{}, args...), pageSize, (page-1)*pageSize)
+	if err := db.Select(&items, query+"\nLIMIT ? OFFSET ?", pageArgs...); err != nil {
+		return nil, 0, fmt.Errorf("query page %d: %w", page, err)
+	}
+
+	return items, total, nil
+}
+
+// PaginateKeyset returns up to limit rows of table ordered by orderColumn,
+// starting after afterValue, or from the beginning when afterValue is nil.
+// Unlike Paginate, each page is a range seek on orderColumn, which should be
+// unique and indexed. nextCursor is the orderColumn value of the last row,
+// to pass as afterValue for the next page, and nil once a page comes back
+// short. T must be a struct with a field mapped to orderColumn.
+func PaginateKeyset[T any](db *sqlx.DB, table string, orderColumn string, afterValue any, limit int) ([]T, any, error) {
+	if limit < 1 {
+		return nil, nil, fmt.Errorf("limit must be at least 1, got %d", limit)
+	}
+
+	columns, err := GetTableColumns(db, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(columns) == 0 {
+		return nil, nil, &ValidationError{Err: ErrTableMissing, Table: table}
+	}
+	column := ""
+	for _, c := range columns {
+		if strings.EqualFold(c.Name, orderColumn) {
+			column = c.Name
+		}
+	}
+	if column == "" {
+		return nil, nil, &ValidationError{Err: ErrColumnMissing, Table: table, Column: orderColumn}
+	}
+
+	query := "SELECT * FROM " + quoteIdent(table)
+	args := []any{}
+	if afterValue != nil {
+		query += " WHERE " + quoteIdent(column) + " > ?"
+		args = append(args, afterValue)
+	}
+	query += " ORDER BY " + quoteIdent(column) + " LIMIT ?"
+	args = append(args, limit)
+
+	items := []T{}
+	if err := db.Select(&items, query, args...); err != nil {
+		return nil, nil, fmt.Errorf("query %s after %v: %w", table, afterValue, err)
+	}
+	if len(items) < limit {
+		return items, nil, nil
+	}
+
+	last := reflect.Indirect(reflect.ValueOf(&items[len(items)-1]).Elem())
+	if last.Kind() != reflect.Struct {
+		return nil, nil, errors.New("keyset pagination needs a struct type")
+	}
+	field := db.Mapper.FieldByName(last, column)
+	if !field.IsValid() {
+		return nil, nil, fmt.Errorf("type %T has no field for column %s", items[0], column)
+	}
+
+	return items, field.Interface(), nil
+}
@@ -0,0 +1,101 @@
+package sqlite_base
+
+import "testing"
+
+type pageEvent struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+const seedEvents = `INSERT INTO events (id, name)
+	WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 25)
+	SELECT i, 'event-' || i FROM n`
+
+func TestPaginate(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT)", seedEvents)
+
+	items, total, err := Paginate[pageEvent](db, "SELECT id, name FROM events WHERE id > ? ORDER BY id", []any{5}, 2, 8, 100)
+	if err != nil {
+		t.Fatalf("paginate failed: %v", err)
+	}
+	if total != 20 {
+		t.Fatalf("expected total 20, got %d", total)
+	}
+	if len(items) != 8 || items[0].ID != 14 || items[7].ID != 21 {
+		t.Fatalf("unexpected page: %+v", items)
+	}
+
+	items, _, err = Paginate[pageEvent](db, "SELECT id, name FROM events ORDER BY id;", nil, 4, 8, 100)
+	if err != nil {
+		t.Fatalf("paginate failed: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != 25 {
+		t.Fatalf("unexpected last page: %+v", items)
+	}
+
+	items, _, err = Paginate[pageEvent](db, "SELECT id, name FROM events ORDER BY id", nil, 10, 8, 100)
+	if err != nil || items == nil || len(items) != 0 {
+		t.Fatalf("expected empty page past the end, got %+v, %v", items, err)
+	}
+
+	ids, _, err := Paginate[int64](db, "SELECT id FROM events ORDER BY id", nil, 2, 100, 10)
+	if err != nil || len(ids) != 10 || ids[0] != 11 {
+		t.Fatalf("expected the page size to be capped at 10, got %v, %v", ids, err)
+	}
+
+	ids, total, err = Paginate[int64](db, "SELECT id FROM events ORDER BY id -- newest last", nil, 1, 5, 100)
+	if err != nil || len(ids) != 5 || total != 25 {
+		t.Fatalf("expected a trailing comment to leave LIMIT in place, got %v, %d, %v", ids, total, err)
+	}
+
+	for _, tt := range []struct{ page, size, max int }{{0, 10, 100}, {1, 0, 100}, {-1, 10, 100}, {1, -5, 100}, {1, 10, 0}} {
+		if _, _, err := Paginate[pageEvent](db, "SELECT id, name FROM events", nil, tt.page, tt.size, tt.max); err == nil {
+			t.Fatalf("expected page %d size %d max %d to be rejected", tt.page, tt.size, tt.max)
+		}
+	}
+}
+
+func TestPaginateKeyset(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT)", seedEvents)
+
+	var seen []int64
+	var cursor any
+	pages := 0
+	for {
+		items, next, err := PaginateKeyset[pageEvent](db, "events", "ID", cursor, 10)
+		if err != nil {
+			t.Fatalf("keyset page %d failed: %v", pages+1, err)
+		}
+		pages++
+		for _, item := range items {
+			seen = append(seen, item.ID)
+		}
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+	if pages != 3 || len(seen) != 25 || seen[0] != 1 || seen[24] != 25 {
+		t.Fatalf("unexpected keyset walk: %d pages, ids %v", pages, seen)
+	}
+
+	items, next, err := PaginateKeyset[pageEvent](db, "events", "id", int64(20), 5)
+	if err != nil || len(items) != 5 || next != int64(25) {
+		t.Fatalf("expected a full last page with a cursor, got %+v, %v, %v", items, next, err)
+	}
+	items, next, err = PaginateKeyset[pageEvent](db, "events", "id", next, 5)
+	if err != nil || len(items) != 0 || next != nil {
+		t.Fatalf("expected an empty page, got %+v, %v, %v", items, next, err)
+	}
+
+	if _, _, err := PaginateKeyset[pageEvent](db, "events", "id; DROP TABLE events", nil, 5); err == nil {
+		t.Fatal("expected unknown order column to be rejected")
+	}
+	if _, _, err := PaginateKeyset[pageEvent](db, "events", "id", nil, 0); err == nil {
+		t.Fatal("expected invalid limit to be rejected")
+	}
+}
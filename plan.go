@@ -0,0 +1,215 @@
+package sqlite_base
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pressly/goose/v3"
+)
+
+type PlannedActionKind string
+
+const (
+	PlanCreateDatabase PlannedActionKind = "create database"
+	PlanSetPageSize    PlannedActionKind = "set page size"
+	PlanApplyMigration PlannedActionKind = "apply migration"
+	PlanValidate       PlannedActionKind = "validate"
+)
+
+// PlannedAction is one step Open would take. Source is the migration file
+// for PlanApplyMigration actions.
+type PlannedAction struct {
+	Kind        PlannedActionKind
+	Description string
+	Source      string
+}
+
+// Plan reports what Open(config) would do without changing anything: whether
+// the database file would be created, which pending migrations would run and
+// which schema validations would be performed. An existing database is only
+// opened read-only to read its migration version.
+func Plan(config Config) ([]PlannedAction, error) {
+	dsn, err := dataSource(config)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(dsn.Path) == "" {
+		return nil, errors.New("path is required")
+	}
+
+	var actions []PlannedAction
+	current := int64(0)
+	exists, err := databaseFileExists(dsn.Path)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if current, err = migrationVersion(config); err != nil {
+			return nil, err
+		}
+	} else {
+		actions = append(actions, PlannedAction{Kind: PlanCreateDatabase, Description: "create database file " + dsn.Path})
+	}
+
+	readOnly := config.ReadOnly || config.Immutable
+	if !readOnly && config.PageSize != 0 {
+		actions = append(actions, PlannedAction{Kind: PlanSetPageSize, Description: fmt.Sprintf("set page_size to %d", config.PageSize)})
+	}
+	if !readOnly {
+		migrations, err := pendingMigrations(config, current)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range migrations {
+			actions = append(actions, PlannedAction{
+				Kind:        PlanApplyMigration,
+				Description: fmt.Sprintf("apply migration %d", m.Version),
+				Source:      m.Source,
+			})
+		}
+	}
+
+	for _, description := range schemaValidations(config.Schema) {
+		actions = append(actions, PlannedAction{Kind: PlanValidate, Description: description})
+	}
+
+	return actions, nil
+}
+
+// databaseFileExists reports whether path names an existing file. URI and
+// in-memory paths are treated as existing so that Plan never creates them.
+func databaseFileExists(path string) (bool, error) {
+	if strings.HasPrefix(path, "file:") || path == ":memory:" {
+		return true, nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("stat database file: %w", err)
+	}
+
+	return true, nil
+}
+
+// migrationVersion reads the highest applied goose version from a read-only
+// connection, or 0 when no migrations have been applied.
+func migrationVersion(config Config) (int64, error) {
+	readOnly := Config{
+		Path:          config.Path,
+		DSN:           config.DSN,
+		ReadOnly:      true,
+		EncryptionKey: config.EncryptionKey,
+		ConnectHook:   config.ConnectHook,
+	}
+	db, err := Open(readOnly)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	exists, err := tableExists(db, goose.TableName())
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var version int64
+	query := fmt.Sprintf("SELECT COALESCE(MAX(version_id), 0) FROM %s WHERE is_applied", quoteIdent(goose.TableName()))
+	if err := db.Get(&version, query); err != nil {
+		return 0, fmt.Errorf("read migration version: %w", err)
+	}
+
+	return version, nil
+}
+
+// gooseMu guards goose's global base file system while migrations are
+// collected.
+var gooseMu sync.Mutex
+
+func pendingMigrations(config Config, current int64) (goose.Migrations, error) {
+	dir := config.MigrationDir
+	if strings.TrimSpace(dir) == "" {
+		return nil, nil
+	}
+
+	var err error
+	if config.MigrationFS != nil {
+		_, err = fs.Stat(config.MigrationFS, path.Clean(dir))
+	} else {
+		_, err = os.Stat(dir)
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("stat migration dir: %w", err)
+	}
+
+	gooseMu.Lock()
+	defer gooseMu.Unlock()
+
+	goose.SetBaseFS(config.MigrationFS)
+	defer goose.SetBaseFS(nil)
+
+	migrations, err := goose.CollectMigrations(dir, current, goose.MaxVersion)
+	if err != nil {
+		if errors.Is(err, goose.ErrNoMigrationFiles) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("collect migrations: %w", err)
+	}
+
+	return migrations, nil
+}
+
+// schemaValidations describes the checks ValidateSchema performs, in order.
+func schemaValidations(schema Schema) []string {
+	tables := make([]string, 0, len(schema.Columns))
+	for table := range schema.Columns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	var checks []string
+	for _, table := range tables {
+		columns := make([]string, 0, len(schema.Columns[table]))
+		for column, columnType := range schema.Columns[table] {
+			columns = append(columns, column+" "+columnType)
+		}
+		sort.Strings(columns)
+		checks = append(checks, fmt.Sprintf("table %s has columns %s", table, strings.Join(columns, ", ")))
+	}
+	for _, table := range schema.StrictTables {
+		checks = append(checks, fmt.Sprintf("table %s is STRICT", table))
+	}
+	for _, table := range schema.WithoutRowidTables {
+		checks = append(checks, fmt.Sprintf("table %s is WITHOUT ROWID", table))
+	}
+	for _, index := range schema.Indexes {
+		checks = append(checks, "index "+index.Name+" exists")
+	}
+	for _, fk := range schema.ForeignKeys {
+		checks = append(checks, "foreign key "+fk.String()+" exists")
+	}
+	for _, check := range schema.Checks {
+		name := check.Name
+		if name == "" {
+			name = check.Expr
+		}
+		checks = append(checks, fmt.Sprintf("table %s has check constraint %s", check.Table, name))
+	}
+	for _, view := range schema.Views {
+		checks = append(checks, "view "+view.Name+" exists")
+	}
+	for _, trigger := range schema.Triggers {
+		checks = append(checks, "trigger "+trigger.Name+" exists")
+	}
+
+	return checks
+}
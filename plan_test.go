@@ -0,0 +1,56 @@
+package sqlite_base
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlan_DoesNotTouchDatabase(t *testing.T) {
+	t.Parallel()
+
+	config := Config{
+		Path:         filepath.Join(t.TempDir(), "app.sqlite"),
+		MigrationDir: "examples/migrations",
+		MigrationFS:  embedMigrations,
+		Schema:       Schema{Columns: map[string]map[string]string{"users": {"role": "TEXT"}}},
+	}
+
+	actions, err := Plan(config)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	kinds := make([]PlannedActionKind, len(actions))
+	for i, action := range actions {
+		kinds[i] = action.Kind
+	}
+	want := []PlannedActionKind{PlanCreateDatabase, PlanApplyMigration, PlanApplyMigration, PlanValidate}
+	if len(kinds) != len(want) {
+		t.Fatalf("unexpected plan: %+v", actions)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("unexpected plan: %+v", actions)
+		}
+	}
+	if actions[1].Source != "examples/migrations/00001_init.sql" {
+		t.Fatalf("unexpected migration source %q", actions[1].Source)
+	}
+	if _, err := os.Stat(config.Path); !os.IsNotExist(err) {
+		t.Fatalf("expected plan not to create the database, stat err: %v", err)
+	}
+
+	db, err := Open(config)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	_ = db.Close()
+
+	actions, err = Plan(config)
+	if err != nil {
+		t.Fatalf("plan after open failed: %v", err)
+	}
+	if len(actions) != 1 || actions[0].Kind != PlanValidate {
+		t.Fatalf("expected only validation after migrating, got %+v", actions)
+	}
+}
@@ -0,0 +1,298 @@
+package sqlite_base
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// dataSource builds the DSN used by Open: config.DSN, or config.Path when no
+// DSN path is given, with the connection-level Config fields applied on top
+// so that every pooled connection is configured identically.
+func dataSource(config Config) (DSN, error) {
+	dsn := config.DSN
+	if dsn.Path == "" {
+		dsn.Path = config.Path
+	}
+
+	if config.CacheSize != 0 && config.CacheSizeBytes != 0 {
+		return DSN{}, errors.New("set either cache size or cache size bytes, not both")
+	}
+	if config.CacheSize != 0 {
+		dsn.CacheSize = config.CacheSize
+	}
+	if config.CacheSizeBytes < 0 {
+		return DSN{}, errors.New("cache size bytes must not be negative")
+	}
+	if config.CacheSizeBytes > 0 {
+		dsn.CacheSize = -int((config.CacheSizeBytes + 1023) / 1024)
+	}
+	if config.Synchronous != "" {
+		dsn.Synchronous = config.Synchronous
+	}
+	if config.BusyHandler != nil {
+		if dsn.BusyTimeout != 0 || dsn.Params.Has("_busy_timeout") || dsn.Params.Has("_timeout") {
+			return DSN{}, errors.New("set either busy timeout or busy handler, not both")
+		}
+		// go-sqlite3 defaults to a 5s busy timeout; disable it so that the
+		// handler sees every SQLITE_BUSY.
+		params := url.Values{}
+		for key, values := range dsn.Params {
+			params[key] = values
+		}
+		params.Set("_busy_timeout", "0")
+		dsn.Params = params
+	}
+	if config.ReadUncommitted && !config.SharedCache {
+		return DSN{}, errors.New("read uncommitted requires shared cache")
+	}
+	if config.SharedCache {
+		dsn.CacheShared = true
+	}
+	if config.ReadOnly || config.Immutable {
+		dsn.Mode = "ro"
+	}
+	if config.Immutable {
+		dsn.Immutable = true
+	}
+
+	if err := dsn.Validate(); err != nil {
+		return DSN{}, err
+	}
+
+	return dsn, nil
+}
+
+// applyPageSize sets PRAGMA page_size, which SQLite only honours before the
+// first table is created or on the next VACUUM outside WAL mode. The pragma
+// only affects the connection it runs on, so it is followed by a VACUUM on
+// the same connection to write the new page size to the file. Asking for a
+// different page size on a populated database is reported as an error rather
+// than silently ignored.
+func applyPageSize(db *sqlx.DB, pageSize int) error {
+	if pageSize == 0 {
+		return nil
+	}
+	if pageSize < 512 || pageSize > 65536 || pageSize&(pageSize-1) != 0 {
+		return fmt.Errorf("page size must be a power of two between 512 and 65536, got %d", pageSize)
+	}
+
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var current int
+	if err := conn.GetContext(ctx, &current, "PRAGMA page_size"); err != nil {
+		return fmt.Errorf("read page size: %w", err)
+	}
+	if current == pageSize {
+		return nil
+	}
+
+	var objects int
+	if err := conn.GetContext(ctx, &objects, "SELECT COUNT(1) FROM sqlite_master"); err != nil {
+		return fmt.Errorf("inspect schema: %w", err)
+	}
+	if objects > 0 {
+		return fmt.Errorf("page size %d cannot be applied to a populated database using page size %d; run VACUUM after changing it", pageSize, current)
+	}
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("PRAGMA page_size = %d", pageSize)); err != nil {
+		return fmt.Errorf("set page size: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	if err := conn.GetContext(ctx, &current, "PRAGMA page_size"); err != nil {
+		return fmt.Errorf("read page size: %w", err)
+	}
+	if current != pageSize {
+		return fmt.Errorf("page size %d was not applied, SQLite kept %d; a database in WAL mode cannot change its page size", pageSize, current)
+	}
+
+	return nil
+}
+
+// checkMmapSize warns when SQLite capped Config.MmapSize at its compile-time
+// SQLITE_MAX_MMAP_SIZE, or ignored it because mmap is unavailable. In-memory
+// databases have no file to map and report no mmap size at all.
+func checkMmapSize(db *sqlx.DB, requested int64, logger Logger) error {
+	var actual int64
+	if err := db.Get(&actual, "PRAGMA mmap_size"); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("read mmap size: %w", err)
+	}
+	if actual < requested {
+		logger.Warnf("mmap_size %d was capped to %d by SQLITE_MAX_MMAP_SIZE", requested, actual)
+	}
+
+	return nil
+}
+
+var dumpedPragmas = []string{
+	"journal_mode",
+	"synchronous",
+	"foreign_keys",
+	"busy_timeout",
+	"cache_size",
+	"page_size",
+	"auto_vacuum",
+	"temp_store",
+	"locking_mode",
+	"encoding",
+}
+
+// DumpPragmas reads a curated set of pragmas that affect behaviour and
+// durability. They are read on a single connection because several of them
+// are per-connection settings.
+func DumpPragmas(db *sqlx.DB) (map[string]string, error) {
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("checkout connection: %w", err)
+	}
+	defer conn.Close()
+
+	pragmas := make(map[string]string, len(dumpedPragmas))
+	for _, name := range dumpedPragmas {
+		var value string
+		if err := conn.GetContext(ctx, &value, "PRAGMA "+name); err != nil {
+			return nil, fmt.Errorf("read pragma %s: %w", name, err)
+		}
+		pragmas[name] = value
+	}
+
+	return pragmas, nil
+}
+
+// creationPragmas can only change on a database without tables.
+var creationPragmas = []string{"encoding", "page_size", "auto_vacuum"}
+
+var pragmaValuePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+var autoVacuumModes = map[string]string{"NONE": "0", "FULL": "1", "INCREMENTAL": "2"}
+
+// ApplyPragmas applies a pragma set captured by DumpPragmas, restricted to the
+// same allowlist. encoding, page_size and auto_vacuum are applied first and
+// only while the database has no tables; requesting a different value on a
+// populated database is an error. The pragmas run on a single connection, so
+// per-connection settings (synchronous, foreign_keys, busy_timeout,
+// cache_size, temp_store, locking_mode) only affect that connection; use
+// Config.Pragmas to configure every pooled connection.
+func ApplyPragmas(db *sqlx.DB, pragmas map[string]string) error {
+	allowed := make(map[string]bool, len(dumpedPragmas))
+	for _, name := range dumpedPragmas {
+		allowed[name] = true
+	}
+	for name, value := range pragmas {
+		if !allowed[name] {
+			return fmt.Errorf("pragma %s is not supported", name)
+		}
+		if !pragmaValuePattern.MatchString(value) {
+			return fmt.Errorf("pragma %s: invalid value %q", name, value)
+		}
+	}
+
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("checkout connection: %w", err)
+	}
+	defer conn.Close()
+
+	var objects int
+	if err := conn.GetContext(ctx, &objects, "SELECT COUNT(1) FROM sqlite_master"); err != nil {
+		return fmt.Errorf("inspect schema: %w", err)
+	}
+
+	creation := make(map[string]bool, len(creationPragmas))
+	for _, name := range creationPragmas {
+		creation[name] = true
+		value, ok := pragmas[name]
+		if !ok {
+			continue
+		}
+
+		var current string
+		if err := conn.GetContext(ctx, &current, "PRAGMA "+name); err != nil {
+			return fmt.Errorf("read pragma %s: %w", name, err)
+		}
+		want := value
+		if name == "auto_vacuum" {
+			if mode, ok := autoVacuumModes[strings.ToUpper(value)]; ok {
+				want = mode
+			}
+		}
+		if strings.EqualFold(current, want) {
+			continue
+		}
+		if objects > 0 {
+			return fmt.Errorf("pragma %s cannot change from %s to %s once the database has tables", name, current, value)
+		}
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("PRAGMA %s = %s", name, quotePragmaValue(value))); err != nil {
+			return fmt.Errorf("set pragma %s: %w", name, err)
+		}
+	}
+
+	names := make([]string, 0, len(pragmas))
+	for name := range pragmas {
+		if !creation[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("PRAGMA %s = %s", name, quotePragmaValue(pragmas[name]))); err != nil {
+			return fmt.Errorf("set pragma %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+var pragmaNamePattern = regexp.MustCompile(`^[A-Za-z_]+$`)
+
+// connectionPragmas turns Config.Pragmas into PRAGMA statements, sorted by
+// name so that every connection is configured in the same order.
+func connectionPragmas(pragmas map[string]string) ([]string, error) {
+	names := make([]string, 0, len(pragmas))
+	for name, value := range pragmas {
+		if !pragmaNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid pragma name %q", name)
+		}
+		if !pragmaValuePattern.MatchString(value) {
+			return nil, fmt.Errorf("pragma %s: invalid value %q", name, value)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stmts := make([]string, 0, len(names))
+	for _, name := range names {
+		stmts = append(stmts, fmt.Sprintf("PRAGMA %s = %s", name, quotePragmaValue(pragmas[name])))
+	}
+
+	return stmts, nil
+}
+
+// quotePragmaValue quotes values such as UTF-8 that are not bare identifiers
+// or numbers.
+func quotePragmaValue(value string) string {
+	if strings.Contains(value, "-") && !strings.HasPrefix(value, "-") {
+		return "'" + value + "'"
+	}
+
+	return value
+}
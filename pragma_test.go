@@ -0,0 +1,415 @@
+package sqlite_base
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestOpen_CacheSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config Config
+		want   int
+	}{
+		{"pages", Config{CacheSize: 4000}, 4000},
+		{"kib", Config{CacheSize: -8000}, -8000},
+		{"bytes", Config{CacheSizeBytes: 64 << 20}, -65536},
+	}
+	for _, tt := range tests {
+		config := tt.config
+		config.Path = filepath.Join(t.TempDir(), "app.sqlite")
+		db, err := Open(config)
+		if err != nil {
+			t.Fatalf("%s: open failed: %v", tt.name, err)
+		}
+
+		var got int
+		if err := db.Get(&got, "PRAGMA cache_size"); err != nil {
+			t.Fatalf("%s: read cache size failed: %v", tt.name, err)
+		}
+		_ = db.Close()
+		if got != tt.want {
+			t.Fatalf("%s: expected cache_size %d, got %d", tt.name, tt.want, got)
+		}
+	}
+
+	_, err := Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite"), CacheSize: 10, CacheSizeBytes: 10})
+	if err == nil {
+		t.Fatal("expected error when both cache size settings are set")
+	}
+}
+
+func TestOpen_PageSize(t *testing.T) {
+	t.Parallel()
+
+	dbPath := filepath.Join(t.TempDir(), "app.sqlite")
+	db, err := Open(Config{Path: dbPath, PageSize: 8192})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	db.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+
+	var pageSize int
+	if err := db.Get(&pageSize, "PRAGMA page_size"); err != nil {
+		t.Fatalf("read page size failed: %v", err)
+	}
+	_ = db.Close()
+	if pageSize != 8192 {
+		t.Fatalf("expected page size 8192, got %d", pageSize)
+	}
+
+	db, err = Open(Config{Path: dbPath, PageSize: 8192})
+	if err != nil {
+		t.Fatalf("reopen with same page size failed: %v", err)
+	}
+	_ = db.Close()
+
+	_, err = Open(Config{Path: dbPath, PageSize: 4096})
+	if err == nil || !strings.Contains(err.Error(), "populated database") {
+		t.Fatalf("expected populated database error, got: %v", err)
+	}
+
+	_, err = Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite"), PageSize: 1000})
+	if err == nil {
+		t.Fatal("expected error for invalid page size")
+	}
+
+	_, err = Open(Config{DSN: DSN{Path: filepath.Join(t.TempDir(), "app.sqlite"), JournalMode: "WAL"}, PageSize: 8192})
+	if err == nil || !strings.Contains(err.Error(), "WAL mode") {
+		t.Fatalf("expected WAL page size error, got: %v", err)
+	}
+}
+
+func TestOpen_PageSizeAppliesToOtherConnections(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite"), PageSize: 8192})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	// Hold the pooled connection Open used so the table is created on another.
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("get connection failed: %v", err)
+	}
+	defer conn.Close()
+	db.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+
+	var pageSize int
+	if err := db.Get(&pageSize, "PRAGMA page_size"); err != nil {
+		t.Fatalf("read page size failed: %v", err)
+	}
+	if pageSize != 8192 {
+		t.Fatalf("expected page size 8192, got %d", pageSize)
+	}
+}
+
+func TestOpen_Synchronous(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite"), Synchronous: "off"})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	var got int
+	if err := db.Get(&got, "PRAGMA synchronous"); err != nil {
+		t.Fatalf("read synchronous failed: %v", err)
+	}
+	if got != 0 {
+		t.Fatalf("expected synchronous=0 (OFF), got %d", got)
+	}
+
+	_, err = Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite"), Synchronous: "SOMETIMES"})
+	if err == nil {
+		t.Fatal("expected error for unknown synchronous mode")
+	}
+}
+
+func TestDumpPragmas(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{DSN: DSN{
+		Path:        filepath.Join(t.TempDir(), "app.sqlite"),
+		JournalMode: "WAL",
+		ForeignKeys: true,
+		BusyTimeout: 2 * time.Second,
+	}})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	pragmas, err := DumpPragmas(db)
+	if err != nil {
+		t.Fatalf("dump pragmas failed: %v", err)
+	}
+
+	want := map[string]string{
+		"journal_mode": "wal",
+		"foreign_keys": "1",
+		"busy_timeout": "2000",
+		"encoding":     "UTF-8",
+		"locking_mode": "normal",
+	}
+	for key, value := range want {
+		if pragmas[key] != value {
+			t.Fatalf("expected %s=%s, got %q", key, value, pragmas[key])
+		}
+	}
+	for _, key := range []string{"synchronous", "cache_size", "page_size", "auto_vacuum", "temp_store"} {
+		if _, ok := pragmas[key]; !ok {
+			t.Fatalf("expected pragma %s in dump", key)
+		}
+	}
+}
+
+func TestApplyPragmas_RoundTrips(t *testing.T) {
+	t.Parallel()
+
+	source, err := Open(Config{DSN: DSN{
+		Path:        filepath.Join(t.TempDir(), "source.sqlite"),
+		Synchronous: SynchronousNormal,
+		BusyTimeout: time.Second,
+	}, PageSize: 8192})
+	if err != nil {
+		t.Fatalf("open source failed: %v", err)
+	}
+	t.Cleanup(func() { _ = source.Close() })
+	source.SetMaxOpenConns(1)
+	source.MustExec("PRAGMA auto_vacuum = INCREMENTAL")
+	source.MustExec("PRAGMA journal_mode = WAL")
+	source.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+
+	captured, err := DumpPragmas(source)
+	if err != nil {
+		t.Fatalf("dump source failed: %v", err)
+	}
+
+	target, err := Open(Config{Path: filepath.Join(t.TempDir(), "target.sqlite")})
+	if err != nil {
+		t.Fatalf("open target failed: %v", err)
+	}
+	t.Cleanup(func() { _ = target.Close() })
+	target.SetMaxOpenConns(1)
+
+	if err := ApplyPragmas(target, captured); err != nil {
+		t.Fatalf("apply pragmas failed: %v", err)
+	}
+	applied, err := DumpPragmas(target)
+	if err != nil {
+		t.Fatalf("dump target failed: %v", err)
+	}
+	if !reflect.DeepEqual(applied, captured) {
+		t.Fatalf("pragmas did not round-trip:\n got: %v\nwant: %v", applied, captured)
+	}
+
+	target.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+	err = ApplyPragmas(target, map[string]string{"page_size": "4096"})
+	if err == nil || !strings.Contains(err.Error(), "once the database has tables") {
+		t.Fatalf("expected populated database error, got: %v", err)
+	}
+	if err := ApplyPragmas(target, map[string]string{"writable_schema": "1"}); err == nil {
+		t.Fatal("expected error for pragma outside the allowlist")
+	}
+	if err := ApplyPragmas(target, map[string]string{"synchronous": "1; DROP TABLE items"}); err == nil {
+		t.Fatal("expected error for invalid pragma value")
+	}
+}
+
+func TestOpen_PragmasRunOnEveryConnection(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{
+		Path:    filepath.Join(t.TempDir(), "app.sqlite"),
+		Pragmas: map[string]string{"temp_store": "MEMORY", "recursive_triggers": "ON"},
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	var conns []*sqlx.Conn
+	for i := 0; i < 3; i++ {
+		conn, err := db.Connx(ctx)
+		if err != nil {
+			t.Fatalf("connx failed: %v", err)
+		}
+		conns = append(conns, conn)
+	}
+	for i, conn := range conns {
+		var tempStore, recursive int
+		if err := conn.GetContext(ctx, &tempStore, "PRAGMA temp_store"); err != nil {
+			t.Fatalf("read temp_store failed: %v", err)
+		}
+		if err := conn.GetContext(ctx, &recursive, "PRAGMA recursive_triggers"); err != nil {
+			t.Fatalf("read recursive_triggers failed: %v", err)
+		}
+		if tempStore != 2 || recursive != 1 {
+			t.Fatalf("connection %d: expected temp_store=2 recursive_triggers=1, got %d %d", i, tempStore, recursive)
+		}
+		_ = conn.Close()
+	}
+}
+
+func TestOpen_RejectsInvalidPragma(t *testing.T) {
+	t.Parallel()
+
+	_, err := Open(Config{
+		Path:    filepath.Join(t.TempDir(), "app.sqlite"),
+		Pragmas: map[string]string{"foreign_keys; DROP TABLE x": "ON"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid pragma name") {
+		t.Fatalf("expected invalid pragma name error, got %v", err)
+	}
+}
+
+func TestOpen_SharedCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	open := func(readUncommitted bool) *sqlx.Conn {
+		db, err := Open(Config{
+			Path:            filepath.Join(t.TempDir(), "app.sqlite"),
+			SharedCache:     true,
+			ReadUncommitted: readUncommitted,
+			BusyHandler:     func(int) bool { return false },
+		})
+		if err != nil {
+			t.Fatalf("open failed: %v", err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+		db.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+
+		writer, err := db.Connx(ctx)
+		if err != nil {
+			t.Fatalf("checkout failed: %v", err)
+		}
+		t.Cleanup(func() { _ = writer.Close() })
+		reader, err := db.Connx(ctx)
+		if err != nil {
+			t.Fatalf("checkout failed: %v", err)
+		}
+		t.Cleanup(func() { _ = reader.Close() })
+
+		for _, stmt := range []string{"BEGIN", "INSERT INTO items (id) VALUES (1)"} {
+			if _, err := writer.ExecContext(ctx, stmt); err != nil {
+				t.Fatalf("exec %q failed: %v", stmt, err)
+			}
+		}
+		t.Cleanup(func() { _, _ = writer.ExecContext(ctx, "ROLLBACK") })
+
+		return reader
+	}
+
+	reader := open(false)
+	var count int
+	err := reader.GetContext(ctx, &count, "SELECT COUNT(*) FROM items")
+	if !IsLocked(err) {
+		t.Fatalf("expected SQLITE_LOCKED reading a table being written, got %v", err)
+	}
+
+	reader = open(true)
+	if err := reader.GetContext(ctx, &count, "SELECT COUNT(*) FROM items"); err != nil {
+		t.Fatalf("read uncommitted failed: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected to see the uncommitted row, got %d", count)
+	}
+
+	_, err = Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite"), ReadUncommitted: true})
+	if err == nil || !strings.Contains(err.Error(), "requires shared cache") {
+		t.Fatalf("expected read uncommitted without shared cache to fail, got %v", err)
+	}
+}
+
+func TestOpen_MmapSize(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db, err := Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite"), MmapSize: 1 << 20})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer db.Close()
+
+	var conns []*sqlx.Conn
+	for i := 0; i < 2; i++ {
+		conn, err := db.Connx(ctx)
+		if err != nil {
+			t.Fatalf("checkout failed: %v", err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	for i, conn := range conns {
+		var size int64
+		if err := conn.GetContext(ctx, &size, "PRAGMA mmap_size"); err != nil {
+			t.Fatalf("read mmap size failed: %v", err)
+		}
+		if size != 1<<20 {
+			t.Fatalf("expected connection %d to use mmap size %d, got %d", i, 1<<20, size)
+		}
+	}
+
+	_, err = Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite"), MmapSize: -1})
+	if err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Fatalf("expected negative mmap size to fail, got %v", err)
+	}
+}
+
+func TestOpen_MmapSizeWarnsWhenCapped(t *testing.T) {
+	t.Parallel()
+
+	logger := &recordingLogger{}
+	db, err := Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite"), MmapSize: 1 << 62, Logger: logger})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer db.Close()
+
+	if !logger.contains("SQLITE_MAX_MMAP_SIZE") {
+		t.Fatalf("expected a warning about the capped mmap size, got %v", logger.messages)
+	}
+}
+
+func BenchmarkMmapSize(b *testing.B) {
+	for _, bench := range []struct {
+		name     string
+		mmapSize int64
+	}{
+		{"off", 0},
+		{"on", 256 << 20},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			db, err := Open(Config{Path: filepath.Join(b.TempDir(), "app.sqlite"), MmapSize: bench.mmapSize})
+			if err != nil {
+				b.Fatalf("open failed: %v", err)
+			}
+			defer db.Close()
+			db.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY, body TEXT)")
+			db.MustExec(`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 10000)
+				INSERT INTO items (id, body) SELECT i, printf('%0200d', i) FROM n`)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				var total int64
+				if err := db.Get(&total, "SELECT SUM(length(body)) FROM items"); err != nil {
+					b.Fatalf("scan failed: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package sqlite_base
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrScanLimitExceeded = errors.New("query scan limit exceeded")
+
+// QueryWithScanLimit refuses to run query when its plan walks every row of a
+// table estimated to hold more than maxRows rows. go-sqlite3 does not expose
+// SQLite's progress handler, so instead of counting VM steps while the query
+// runs the compiled bytecode is inspected up front: cursors that are rewound
+// rather than seeked are full scans of their table or index. A scan that a
+// LIMIT (plus OFFSET) of at most maxRows stops early is allowed, although a
+// WHERE clause the scan filters on can make it read more rows than it
+// returns. Table sizes are estimated without scanning, from sqlite_stat1
+// when ANALYZE has run and from max(rowid) otherwise; only WITHOUT ROWID
+// tables without statistics are counted, and then only up to maxRows + 1.
+func QueryWithScanLimit(db *sqlx.DB, maxRows int64, query string, args ...any) (*sqlx.Rows, error) {
+	if maxRows <= 0 {
+		return nil, errors.New("max rows must be positive")
+	}
+
+	scans, err := fullScans(db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, scan := range scans {
+		if scan.limited && scan.limit <= maxRows {
+			continue
+		}
+		rows, err := estimateRows(db, scan.table, maxRows)
+		if err != nil {
+			return nil, err
+		}
+		if rows > maxRows {
+			return nil, fmt.Errorf("%w: full scan of %s reads about %d rows, limit is %d", ErrScanLimitExceeded, scan.table, rows, maxRows)
+		}
+	}
+
+	return db.Queryx(query, args...)
+}
+
+// fullScan is a loop over every row of table. When limited is set, a LIMIT
+// ends the loop after limit rows.
+type fullScan struct {
+	table   string
+	limited bool
+	limit   int64
+}
+
+type explainOp struct {
+	opcode     string
+	p1, p2, p3 int64
+}
+
+func fullScans(db *sqlx.DB, query string, args ...any) ([]fullScan, error) {
+	rows, err := db.Queryx("EXPLAIN "+query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("explain query: %w", err)
+	}
+	defer rows.Close()
+
+	var ops []explainOp
+	for rows.Next() {
+		cols, err := rows.SliceScan()
+		if err != nil {
+			return nil, fmt.Errorf("scan explain row: %w", err)
+		}
+		op := explainOp{}
+		op.opcode, _ = cols[1].(string)
+		op.p1, _ = cols[2].(int64)
+		op.p2, _ = cols[3].(int64)
+		op.p3, _ = cols[4].(int64)
+		ops = append(ops, op)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read explain rows: %w", err)
+	}
+
+	// Registers loaded with a constant or a bound parameter, which is where
+	// LIMIT and OFFSET values live.
+	registers := make(map[int64]int64)
+	for _, op := range ops {
+		switch op.opcode {
+		case "Integer":
+			registers[op.p2] = op.p1
+		case "Variable":
+			if op.p1 >= 1 && int(op.p1) <= len(args) {
+				if v, ok := integerArg(args[op.p1-1]); ok {
+					registers[op.p2] = v
+				}
+			}
+		}
+	}
+
+	rootPages := make(map[int64]int64)
+	var scans []fullScan
+	for addr, op := range ops {
+		switch op.opcode {
+		case "OpenRead":
+			if op.p3 == 0 {
+				rootPages[op.p1] = op.p2
+			}
+		case "Rewind", "Last":
+			page, ok := rootPages[op.p1]
+			if !ok {
+				continue
+			}
+			var table string
+			err := db.Get(&table, `SELECT tbl_name FROM sqlite_master WHERE rootpage = ? AND type IN ('table', 'index')`, page)
+			if err != nil {
+				return nil, fmt.Errorf("resolve root page %d: %w", page, err)
+			}
+			scan := fullScan{table: table}
+			scan.limit, scan.limited = scanLimit(ops, addr, registers)
+			scans = append(scans, scan)
+		}
+	}
+
+	return scans, nil
+}
+
+// scanLimit reports the LIMIT that ends the loop starting at ops[start],
+// found as a DecrJumpZero inside the loop that jumps out of it, plus the
+// OFFSET rows skipped before the limit counts down.
+func scanLimit(ops []explainOp, start int, registers map[int64]int64) (int64, bool) {
+	end := ops[start].p2
+	for addr := int64(start) + 1; addr < end && addr < int64(len(ops)); addr++ {
+		op := ops[addr]
+		if op.opcode != "DecrJumpZero" || op.p2 < end {
+			continue
+		}
+		limit, ok := registers[op.p1]
+		if !ok {
+			return 0, false
+		}
+		for _, offset := range ops {
+			if offset.opcode == "OffsetLimit" && offset.p1 == op.p1 {
+				skipped, ok := registers[offset.p3]
+				if !ok {
+					return 0, false
+				}
+				limit += max(skipped, 0)
+			}
+		}
+
+		return limit, true
+	}
+
+	return 0, false
+}
+
+func integerArg(arg any) (int64, bool) {
+	switch v := arg.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+// estimateRows returns the number of rows in table without scanning it: the
+// row count ANALYZE stored in sqlite_stat1, or max(rowid), which can only
+// overcount. A WITHOUT ROWID table without statistics is counted, stopping
+// after atMost + 1 rows.
+func estimateRows(db *sqlx.DB, table string, atMost int64) (int64, error) {
+	var hasStats bool
+	if err := db.Get(&hasStats, `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'sqlite_stat1')`); err != nil {
+		return 0, fmt.Errorf("check statistics: %w", err)
+	}
+	if hasStats {
+		var stats []string
+		if err := db.Select(&stats, `SELECT stat FROM sqlite_stat1 WHERE tbl = ? COLLATE NOCASE`, table); err != nil {
+			return 0, fmt.Errorf("read statistics for %s: %w", table, err)
+		}
+		for _, stat := range stats {
+			if fields := strings.Fields(stat); len(fields) > 0 {
+				if rows, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+					return rows, nil
+				}
+			}
+		}
+	}
+
+	createSQL, err := tableSQL(db, table)
+	if err != nil {
+		return 0, err
+	}
+	var rows int64
+	if hasTableOption(createSQL, "WITHOUT ROWID") {
+		err = db.Get(&rows, fmt.Sprintf("SELECT COUNT(*) FROM (SELECT 1 FROM %s LIMIT ?)", quoteIdent(table)), atMost+1)
+	} else {
+		err = db.Get(&rows, "SELECT COALESCE(MAX(rowid), 0) FROM "+quoteIdent(table))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("estimate rows in %s: %w", table, err)
+	}
+
+	return rows, nil
+}
+
+// QueryInto runs query and inserts every result row into destTable inside a
+// single transaction, matching result columns to destination columns by name.
+// It returns the number of rows inserted.
+func QueryInto(db *sqlx.DB, destTable, query string, args ...any) (int64, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var destColumns []string
+	if err := tx.Select(&destColumns, `SELECT name FROM pragma_table_info(?)`, destTable); err != nil {
+		return 0, fmt.Errorf("query table info for %s: %w", destTable, err)
+	}
+	if len(destColumns) == 0 {
+		return 0, fmt.Errorf("table %s does not exist", destTable)
+	}
+	known := make(map[string]string, len(destColumns))
+	for _, column := range destColumns {
+		known[strings.ToLower(column)] = column
+	}
+
+	rows, err := tx.Queryx(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("run source query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("read source columns: %w", err)
+	}
+	names := make([]string, len(columns))
+	params := make([]string, len(columns))
+	for i, column := range columns {
+		name, ok := known[strings.ToLower(column)]
+		if !ok {
+			return 0, fmt.Errorf("table %s has no column %s", destTable, column)
+		}
+		names[i] = quoteIdent(name)
+		params[i] = "?"
+	}
+
+	stmt, err := tx.Preparex(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(destTable), strings.Join(names, ", "), strings.Join(params, ", ")))
+	if err != nil {
+		return 0, fmt.Errorf("prepare insert into %s: %w", destTable, err)
+	}
+	defer stmt.Close()
+
+	var inserted int64
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return 0, fmt.Errorf("scan source row: %w", err)
+		}
+		if _, err := stmt.Exec(values...); err != nil {
+			return 0, fmt.Errorf("insert into %s: %w", destTable, err)
+		}
+		inserted++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("read source rows: %w", err)
+	}
+	_ = rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return inserted, nil
+}
+
+// QueryMaps runs query and returns every row as a map keyed by column name,
+// for callers that do not know the result shape in advance. NULLs are nil,
+// and []byte values of columns declared with TEXT affinity are converted to
+// strings; values of expressions and BLOB columns are left as returned by
+// the driver.
+func QueryMaps(db *sqlx.DB, query string, args ...any) ([]map[string]any, error) {
+	rows, err := db.Queryx(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("run query: %w", err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("read column types: %w", err)
+	}
+	var textColumns []string
+	for _, columnType := range columnTypes {
+		if typeAffinity(columnType.DatabaseTypeName()) == "TEXT" {
+			textColumns = append(textColumns, columnType.Name())
+		}
+	}
+
+	results := []map[string]any{}
+	for rows.Next() {
+		row := make(map[string]any, len(columnTypes))
+		if err := rows.MapScan(row); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		for _, name := range textColumns {
+			if b, ok := row[name].([]byte); ok {
+				row[name] = string(b)
+			}
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read rows: %w", err)
+	}
+
+	return results, nil
+}
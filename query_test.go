@@ -0,0 +1,213 @@
+package sqlite_base
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestQueryWithScanLimit(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT NOT NULL)",
+		"INSERT INTO events (kind) SELECT 'click' FROM (WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 500) SELECT i FROM n)",
+	)
+
+	_, err := QueryWithScanLimit(db, 100, "SELECT * FROM events WHERE kind = ?", "click")
+	if !errors.Is(err, ErrScanLimitExceeded) {
+		t.Fatalf("expected scan limit error, got: %v", err)
+	}
+
+	rows, err := QueryWithScanLimit(db, 100, "SELECT * FROM events WHERE id = ?", 42)
+	if err != nil {
+		t.Fatalf("indexed lookup failed: %v", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read rows failed: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 row, got %d", count)
+	}
+}
+
+func TestQueryWithScanLimit_HonoursLimit(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT NOT NULL)",
+		"INSERT INTO events (kind) SELECT 'click' FROM (WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 500) SELECT i FROM n)",
+	)
+
+	for _, tc := range []struct {
+		query string
+		args  []any
+	}{
+		{"SELECT * FROM events LIMIT 10", nil},
+		{"SELECT * FROM events WHERE kind = ? LIMIT ?", []any{"click", 10}},
+		{"SELECT * FROM events LIMIT 10 OFFSET 90", nil},
+	} {
+		rows, err := QueryWithScanLimit(db, 100, tc.query, tc.args...)
+		if err != nil {
+			t.Fatalf("expected %q to run, got: %v", tc.query, err)
+		}
+		_ = rows.Close()
+	}
+
+	for _, query := range []string{
+		"SELECT * FROM events LIMIT 200",
+		"SELECT * FROM events LIMIT 10 OFFSET 95",
+		"SELECT * FROM events ORDER BY kind LIMIT 10",
+	} {
+		if _, err := QueryWithScanLimit(db, 100, query); !errors.Is(err, ErrScanLimitExceeded) {
+			t.Fatalf("expected %q to exceed the scan limit, got: %v", query, err)
+		}
+	}
+}
+
+func TestQueryWithScanLimit_UsesEstimates(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT NOT NULL)",
+		"INSERT INTO events (id, kind) VALUES (1, 'click'), (1000, 'view')",
+		"CREATE TABLE tags (name TEXT PRIMARY KEY) WITHOUT ROWID",
+		"INSERT INTO tags (name) SELECT 'tag' || i FROM (WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 150) SELECT i FROM n)",
+		"CREATE TABLE small (id INTEGER PRIMARY KEY, body TEXT)",
+		"INSERT INTO small (body) VALUES ('a'), ('b'), ('c')",
+	)
+
+	if _, err := QueryWithScanLimit(db, 100, "SELECT * FROM events"); !errors.Is(err, ErrScanLimitExceeded) {
+		t.Fatalf("expected max(rowid) to exceed the scan limit, got: %v", err)
+	}
+	if _, err := QueryWithScanLimit(db, 100, "SELECT * FROM tags"); !errors.Is(err, ErrScanLimitExceeded) {
+		t.Fatalf("expected the WITHOUT ROWID table to exceed the scan limit, got: %v", err)
+	}
+	rows, err := QueryWithScanLimit(db, 100, "SELECT * FROM small")
+	if err != nil {
+		t.Fatalf("small scan failed: %v", err)
+	}
+	_ = rows.Close()
+
+	db.MustExec("ANALYZE")
+	db.MustExec("UPDATE sqlite_stat1 SET stat = '5000' WHERE tbl = 'small'")
+	if _, err := QueryWithScanLimit(db, 100, "SELECT * FROM small"); !errors.Is(err, ErrScanLimitExceeded) {
+		t.Fatalf("expected sqlite_stat1 to exceed the scan limit, got: %v", err)
+	}
+}
+
+func TestQueryContext_CancelInterruptsRunningQuery(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, err := sql.Open(sleepDriverName, ":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	db := sqlx.NewDb(sqlDB, "sqlite3")
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	var total int64
+	err = db.GetContext(ctx, &total, "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000) SELECT SUM(sleep_ms(20)) FROM n")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("query was not interrupted promptly, took %s", elapsed)
+	}
+}
+
+const sleepDriverName = "sqlite3_sleep_test"
+
+func init() {
+	sql.Register(sleepDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("sleep_ms", func(ms int64) int64 {
+				time.Sleep(time.Duration(ms) * time.Millisecond)
+				return ms
+			}, false)
+		},
+	})
+}
+
+func TestQueryInto_CopiesFilteredRows(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, active INTEGER NOT NULL)",
+		"INSERT INTO users (name, active) VALUES ('alice', 1), ('bob', 0), ('carol', 1)",
+		"CREATE TABLE active_users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, copied_at TEXT)",
+	)
+
+	inserted, err := QueryInto(db, "active_users", "SELECT id, name FROM users WHERE active = ?", 1)
+	if err != nil {
+		t.Fatalf("query into failed: %v", err)
+	}
+	if inserted != 2 {
+		t.Fatalf("expected 2 rows inserted, got %d", inserted)
+	}
+
+	var names []string
+	if err := db.Select(&names, "SELECT name FROM active_users ORDER BY id"); err != nil {
+		t.Fatalf("select failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "alice" || names[1] != "carol" {
+		t.Fatalf("unexpected copied rows: %v", names)
+	}
+
+	if _, err := QueryInto(db, "active_users", "SELECT id, active FROM users"); err == nil {
+		t.Fatal("expected error for column missing from destination")
+	}
+}
+
+func TestQueryMaps(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT, data BLOB, rating REAL)",
+		"INSERT INTO notes (id, body, data, rating) VALUES (1, CAST('hello' AS BLOB), x'0102', 4.5), (2, NULL, NULL, NULL)",
+	)
+
+	rows, err := QueryMaps(db, "SELECT id, body, data, rating FROM notes ORDER BY id")
+	if err != nil {
+		t.Fatalf("query maps failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+
+	first := rows[0]
+	if first["id"] != int64(1) || first["rating"] != 4.5 {
+		t.Fatalf("unexpected numeric values: %#v", first)
+	}
+	if first["body"] != "hello" {
+		t.Fatalf("expected text column as string, got %#v", first["body"])
+	}
+	if data, ok := first["data"].([]byte); !ok || len(data) != 2 {
+		t.Fatalf("expected blob column as bytes, got %#v", first["data"])
+	}
+	for _, column := range []string{"body", "data", "rating"} {
+		if value, ok := rows[1][column]; !ok || value != nil {
+			t.Fatalf("expected nil for NULL %s, got %#v", column, value)
+		}
+	}
+
+	empty, err := QueryMaps(db, "SELECT id FROM notes WHERE id > ?", 10)
+	if err != nil || len(empty) != 0 {
+		t.Fatalf("expected no rows, got %v, %v", empty, err)
+	}
+}
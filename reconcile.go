@@ -0,0 +1,116 @@
+package sqlite_base
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ReconcileSchema repairs the one kind of drift SQLite can fix in place: for
+// every column of expectedColumns (in the form of Schema.Columns) missing
+// from its table, the matching spec in tableSchemas is added with ALTER TABLE
+// ... ADD COLUMN. Everything else is still reported as a ValidationError: a
+// missing table, a missing column without a spec, and a column whose type
+// does not match. The ALTER statements run in one transaction and, once it
+// commits, each is logged through logger (which may be nil) and the applied
+// statements are returned.
+func ReconcileSchema(db *sqlx.DB, tableSchemas map[string][]ColumnSpec, expectedColumns map[string]map[string]string, logger Logger) ([]string, error) {
+	logger = loggerOrNop(logger)
+
+	tables := make([]string, 0, len(expectedColumns))
+	for table := range expectedColumns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	var stmts []string
+	for _, table := range tables {
+		tableStmts, err := reconcileTable(db, table, tableSchemas[table], expectedColumns[table])
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, tableStmts...)
+	}
+	if len(stmts) == 0 {
+		return nil, nil
+	}
+
+	err := WithTx(db, func(tx *sqlx.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("reconcile schema: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range stmts {
+		logger.Warnf("reconcile schema: %s", stmt)
+	}
+
+	return stmts, nil
+}
+
+func reconcileTable(db *sqlx.DB, table string, specs []ColumnSpec, expected map[string]string) ([]string, error) {
+	exists, err := tableExists(db, table)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, &ValidationError{Err: ErrTableMissing, Table: table}
+	}
+
+	columns, err := GetTableColumns(db, table)
+	if err != nil {
+		return nil, err
+	}
+	actual := make(map[string]string, len(columns))
+	for _, column := range columns {
+		actual[strings.ToLower(column.Name)] = column.Type
+	}
+
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var stmts []string
+	for _, name := range names {
+		if actualType, ok := actual[strings.ToLower(name)]; ok {
+			if !CaseInsensitiveMatch(expected[name], actualType) {
+				return nil, &ValidationError{Err: ErrColumnTypeMismatch, Table: table, Column: name, Expected: expected[name], Actual: actualType}
+			}
+			continue
+		}
+
+		spec, ok := findColumnSpec(specs, name)
+		if !ok {
+			return nil, &ValidationError{Err: ErrColumnMissing, Table: table, Column: name}
+		}
+		if !CaseInsensitiveMatch(expected[name], spec.Type) {
+			return nil, fmt.Errorf("table %s: column %s spec has type %s, expected %s", table, name, spec.Type, expected[name])
+		}
+		stmt, err := addColumnSQL(table, spec)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+
+	return stmts, nil
+}
+
+func findColumnSpec(specs []ColumnSpec, name string) (ColumnSpec, bool) {
+	for _, spec := range specs {
+		if strings.EqualFold(spec.Name, name) {
+			return spec, true
+		}
+	}
+
+	return ColumnSpec{}, false
+}
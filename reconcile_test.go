@@ -0,0 +1,108 @@
+package sqlite_base
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReconcileSchema_AddsMissingColumns(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)",
+		"INSERT INTO users (email) VALUES ('a@example.com')",
+	)
+	logger := &recordingLogger{}
+
+	specs := map[string][]ColumnSpec{
+		"users": {
+			{Name: "status", Type: "TEXT", NotNull: true, Default: "'active'"},
+			{Name: "nickname", Type: "TEXT"},
+		},
+	}
+	expected := map[string]map[string]string{
+		"users": {"id": "INTEGER", "email": "TEXT", "status": "TEXT", "nickname": "TEXT"},
+	}
+
+	stmts, err := ReconcileSchema(db, specs, expected, logger)
+	if err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if len(stmts) != 2 {
+		t.Fatalf("expected 2 statements, got %v", stmts)
+	}
+	if !logger.contains(`ADD COLUMN "status" TEXT NOT NULL DEFAULT 'active'`) {
+		t.Fatalf("expected change to be logged, got %v", logger.messages)
+	}
+	if err := ValidateSchema(db, Schema{Columns: expected}); err != nil {
+		t.Fatalf("validate after reconcile failed: %v", err)
+	}
+
+	var status string
+	if err := db.Get(&status, "SELECT status FROM users"); err != nil {
+		t.Fatalf("select failed: %v", err)
+	}
+	if status != "active" {
+		t.Fatalf("expected default for existing row, got %q", status)
+	}
+
+	stmts, err = ReconcileSchema(db, specs, expected, nil)
+	if err != nil || len(stmts) != 0 {
+		t.Fatalf("expected no changes on second run, got %v, %v", stmts, err)
+	}
+}
+
+func TestReconcileSchema_RejectsRiskyDrift(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, age TEXT)")
+
+	specs := map[string][]ColumnSpec{"users": {{Name: "email", Type: "TEXT"}}}
+	_, err := ReconcileSchema(db, specs, map[string]map[string]string{
+		"users": {"age": "INTEGER", "email": "TEXT"},
+	}, nil)
+	if !errors.Is(err, ErrColumnTypeMismatch) {
+		t.Fatalf("expected type mismatch, got %v", err)
+	}
+
+	_, err = ReconcileSchema(db, specs, map[string]map[string]string{
+		"users": {"email": "TEXT", "phone": "TEXT"},
+	}, nil)
+	if !errors.Is(err, ErrColumnMissing) {
+		t.Fatalf("expected missing column without spec, got %v", err)
+	}
+
+	columns, err := GetTableColumns(db, "users")
+	if err != nil {
+		t.Fatalf("read columns failed: %v", err)
+	}
+	if len(columns) != 2 {
+		t.Fatalf("expected no column to be added on failure, got %d columns", len(columns))
+	}
+
+	_, err = ReconcileSchema(db, nil, map[string]map[string]string{"orders": {"id": "INTEGER"}}, nil)
+	if !errors.Is(err, ErrTableMissing) {
+		t.Fatalf("expected missing table, got %v", err)
+	}
+}
+
+func TestReconcileSchema_LogsNothingWhenRolledBack(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE users (id INTEGER PRIMARY KEY)")
+	logger := &recordingLogger{}
+
+	specs := map[string][]ColumnSpec{"users": {
+		{Name: "email", Type: "TEXT"},
+		{Name: "token", Type: "TEXT", Default: "(1 +"},
+	}}
+	_, err := ReconcileSchema(db, specs, map[string]map[string]string{
+		"users": {"email": "TEXT", "token": "TEXT"},
+	}, logger)
+	if err == nil {
+		t.Fatal("expected the malformed default to fail")
+	}
+	if len(logger.messages) != 0 {
+		t.Fatalf("expected no statements to be logged, got %v", logger.messages)
+	}
+}
@@ -0,0 +1,619 @@
+package sqlite_base
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Schema describes objects the database is expected to contain. ValidateSchema
+// compares it against the live database; the zero value validates nothing.
+type Schema struct {
+	// Columns maps table name to the expected declared type of each column.
+	// Columns present in the database but absent here are ignored.
+	Columns map[string]map[string]string
+	// Tables are validated column by column, including nullability, primary
+	// keys, defaults and uniqueness; InitSchema also creates them.
+	Tables      []TableDefinition
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+	Views       []View
+	Triggers    []Trigger
+	Checks      []Check
+	// GeneratedColumns lists columns that must be generated columns, which
+	// needs SQLite 3.31.0 or later.
+	GeneratedColumns []GeneratedColumn
+	// StrictTables lists tables that must have been created as STRICT, which
+	// needs SQLite 3.37.0 or later.
+	StrictTables []string
+	// WithoutRowidTables lists tables that must have been created WITHOUT
+	// ROWID.
+	WithoutRowidTables []string
+	// FTS5Tables lists tables that must be FTS5 virtual tables. Their
+	// columns can be checked through Columns like those of any table.
+	FTS5Tables []string
+	// TypeComparator decides whether a column type matches Columns and
+	// Tables; nil means CaseInsensitiveMatch.
+	TypeComparator TypeComparator
+}
+
+// View asserts that a view exists. When SQL is set, the stored definition
+// must match it, ignoring whitespace, identifier quoting and case.
+type View struct {
+	Name string
+	SQL  string
+}
+
+// Trigger asserts that a trigger exists, optionally on Table and with the
+// given SQL, compared like View.SQL.
+type Trigger struct {
+	Name  string
+	Table string
+	SQL   string
+}
+
+// GeneratedColumn asserts that Table.Column is a generated column. Kind is
+// "VIRTUAL" or "STORED"; empty accepts either.
+type GeneratedColumn struct {
+	Table  string
+	Column string
+	Kind   string
+}
+
+type Index struct {
+	Name    string
+	Table   string
+	Columns []string
+	Unique  bool
+}
+
+// ForeignKey is matched on Table, Column and ParentTable. OnDelete and
+// OnUpdate are compared only when set; SQLite reports "NO ACTION" by default.
+type ForeignKey struct {
+	Table        string
+	Column       string
+	ParentTable  string
+	ParentColumn string
+	OnDelete     string
+	OnUpdate     string
+}
+
+func (fk ForeignKey) String() string {
+	return fmt.Sprintf("%s.%s -> %s.%s", fk.Table, fk.Column, fk.ParentTable, fk.ParentColumn)
+}
+
+func ValidateSchema(db *sqlx.DB, schema Schema) error {
+	tables := make([]string, 0, len(schema.Columns))
+	for table := range schema.Columns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		if err := validateColumns(db, table, schema.Columns[table], schema.TypeComparator); err != nil {
+			return err
+		}
+	}
+	for _, table := range schema.Tables {
+		if err := validateTableDefinition(db, table, schema.TypeComparator); err != nil {
+			return err
+		}
+	}
+	for _, column := range schema.GeneratedColumns {
+		if err := validateGeneratedColumn(db, column); err != nil {
+			return err
+		}
+	}
+	if len(schema.StrictTables) > 0 {
+		if err := requireStrictSupport(db); err != nil {
+			return err
+		}
+	}
+	for _, table := range schema.StrictTables {
+		strict, err := IsStrictTable(db, table)
+		if err != nil {
+			return err
+		}
+		if !strict {
+			return fmt.Errorf("table %s is not STRICT", table)
+		}
+	}
+	for _, table := range schema.WithoutRowidTables {
+		withoutRowid, err := IsWithoutRowidTable(db, table)
+		if err != nil {
+			return err
+		}
+		if !withoutRowid {
+			return fmt.Errorf("table %s is not WITHOUT ROWID", table)
+		}
+	}
+	for _, table := range schema.FTS5Tables {
+		fts5, err := IsFTS5Table(db, table)
+		if err != nil {
+			return err
+		}
+		if !fts5 {
+			return fmt.Errorf("table %s is not an FTS5 table", table)
+		}
+	}
+	for _, index := range schema.Indexes {
+		if err := validateIndex(db, index); err != nil {
+			return err
+		}
+	}
+	for _, fk := range schema.ForeignKeys {
+		if err := validateForeignKey(db, fk); err != nil {
+			return err
+		}
+	}
+	for _, check := range schema.Checks {
+		if err := validateCheck(db, check); err != nil {
+			return err
+		}
+	}
+	for _, view := range schema.Views {
+		if err := validateObject(db, "view", view.Name, "", view.SQL); err != nil {
+			return err
+		}
+	}
+	for _, trigger := range schema.Triggers {
+		if err := validateObject(db, "trigger", trigger.Name, trigger.Table, trigger.SQL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func tableExists(db *sqlx.DB, table string) (bool, error) {
+	var count int
+	err := db.Get(&count, `SELECT COUNT(1) FROM sqlite_master WHERE type = 'table' AND name = ? COLLATE NOCASE`, table)
+	if err != nil {
+		return false, fmt.Errorf("query table %s: %w", table, err)
+	}
+
+	return count > 0, nil
+}
+
+// ColumnInfo is one row of PRAGMA table_xinfo. It is scanned by column name,
+// so it keeps working when SQLite adds columns to the pragma.
+type ColumnInfo struct {
+	CID          int            `db:"cid"`
+	Name         string         `db:"name"`
+	Type         string         `db:"type"`
+	NotNull      bool           `db:"notnull"`
+	DefaultValue sql.NullString `db:"dflt_value"`
+	PrimaryKey   int            `db:"pk"`
+	// Hidden is 2 for VIRTUAL and 3 for STORED generated columns.
+	Hidden int `db:"hidden"`
+}
+
+// Generated returns "VIRTUAL" or "STORED" for a generated column and an
+// empty string otherwise.
+func (c ColumnInfo) Generated() string {
+	switch c.Hidden {
+	case 2:
+		return "VIRTUAL"
+	case 3:
+		return "STORED"
+	}
+
+	return ""
+}
+
+// GetTableColumns returns the columns of table in declaration order,
+// including generated columns, which PRAGMA table_info leaves out. It reads
+// PRAGMA table_xinfo on SQLite 3.26.0 and later and falls back to table_info
+// on older libraries, which predate generated columns anyway. Hidden columns
+// of virtual tables are skipped. A missing table yields no columns.
+func GetTableColumns(db *sqlx.DB, table string) ([]ColumnInfo, error) {
+	version, err := sqliteVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT cid, name, type, "notnull", dflt_value, pk, hidden FROM pragma_table_xinfo(?) WHERE hidden <> 1 ORDER BY cid`
+	if !versionAtLeast(version, 3, 26, 0) {
+		query = `SELECT cid, name, type, "notnull", dflt_value, pk, 0 AS hidden FROM pragma_table_info(?) ORDER BY cid`
+	}
+
+	var columns []ColumnInfo
+	if err := db.Select(&columns, query, table); err != nil {
+		return nil, fmt.Errorf("query table info for %s: %w", table, err)
+	}
+
+	return columns, nil
+}
+
+func validateColumns(db *sqlx.DB, table string, expected map[string]string, match TypeComparator) error {
+	if match == nil {
+		match = CaseInsensitiveMatch
+	}
+
+	exists, err := tableExists(db, table)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &ValidationError{Err: ErrTableMissing, Table: table}
+	}
+
+	strict, err := IsStrictTable(db, table)
+	if err != nil {
+		return err
+	}
+
+	columns, err := GetTableColumns(db, table)
+	if err != nil {
+		return err
+	}
+	actual := make(map[string]string, len(columns))
+	for _, column := range columns {
+		actual[strings.ToLower(column.Name)] = column.Type
+	}
+
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		actualType, ok := actual[strings.ToLower(name)]
+		if !ok {
+			return &ValidationError{Err: ErrColumnMissing, Table: table, Column: name}
+		}
+		if strict && !strictTypes[strings.ToUpper(expected[name])] {
+			return fmt.Errorf("table %s: column %s expected type %s is not allowed in a STRICT table", table, name, expected[name])
+		}
+		if !match(expected[name], actualType) {
+			return &ValidationError{Err: ErrColumnTypeMismatch, Table: table, Column: name, Expected: expected[name], Actual: actualType}
+		}
+	}
+
+	return nil
+}
+
+func validateGeneratedColumn(db *sqlx.DB, expected GeneratedColumn) error {
+	version, err := sqliteVersion(db)
+	if err != nil {
+		return err
+	}
+	if !versionAtLeast(version, 3, 31, 0) {
+		return fmt.Errorf("generated columns require SQLite 3.31.0, running %d.%d.%d", version[0], version[1], version[2])
+	}
+
+	columns, err := GetTableColumns(db, expected.Table)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return &ValidationError{Err: ErrTableMissing, Table: expected.Table}
+	}
+	for _, column := range columns {
+		if !strings.EqualFold(column.Name, expected.Column) {
+			continue
+		}
+		kind := column.Generated()
+		if kind == "" {
+			return fmt.Errorf("table %s: column %s is not a generated column", expected.Table, expected.Column)
+		}
+		if expected.Kind != "" && !strings.EqualFold(expected.Kind, kind) {
+			return fmt.Errorf("table %s: generated column %s expected %s, got %s", expected.Table, expected.Column, strings.ToUpper(expected.Kind), kind)
+		}
+		return nil
+	}
+
+	return &ValidationError{Err: ErrColumnMissing, Table: expected.Table, Column: expected.Column}
+}
+
+// SnapshotExpectedColumns reads the declared column types of the given
+// tables, or of every table when none is named, in the form Schema.Columns
+// expects, to bootstrap expectations for an existing database.
+func SnapshotExpectedColumns(db *sqlx.DB, tables ...string) (map[string]map[string]string, error) {
+	if len(tables) == 0 {
+		var err error
+		if tables, err = ListTables(db); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot := make(map[string]map[string]string, len(tables))
+	for _, table := range tables {
+		exists, err := tableExists(db, table)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, &ValidationError{Err: ErrTableMissing, Table: table}
+		}
+
+		columns, err := GetTableColumns(db, table)
+		if err != nil {
+			return nil, err
+		}
+		snapshot[table] = make(map[string]string, len(columns))
+		for _, column := range columns {
+			snapshot[table][column.Name] = column.Type
+		}
+	}
+
+	return snapshot, nil
+}
+
+// strictTypes are the only column types a STRICT table accepts.
+var strictTypes = map[string]bool{"INT": true, "INTEGER": true, "REAL": true, "TEXT": true, "BLOB": true, "ANY": true}
+
+// IsStrictTable reports whether table was created with the STRICT option.
+func IsStrictTable(db *sqlx.DB, table string) (bool, error) {
+	createSQL, err := tableSQL(db, table)
+	if err != nil {
+		return false, err
+	}
+
+	return hasTableOption(createSQL, "STRICT"), nil
+}
+
+// IsWithoutRowidTable reports whether table was created WITHOUT ROWID.
+func IsWithoutRowidTable(db *sqlx.DB, table string) (bool, error) {
+	createSQL, err := tableSQL(db, table)
+	if err != nil {
+		return false, err
+	}
+
+	return hasTableOption(createSQL, "WITHOUT ROWID"), nil
+}
+
+func requireStrictSupport(db *sqlx.DB) error {
+	version, err := sqliteVersion(db)
+	if err != nil {
+		return err
+	}
+	if !versionAtLeast(version, 3, 37, 0) {
+		return fmt.Errorf("STRICT tables require SQLite 3.37.0, running %d.%d.%d", version[0], version[1], version[2])
+	}
+
+	return nil
+}
+
+// ValidateExactColumns checks that each table has exactly the listed columns
+// in the listed order, failing on any missing, extra or reordered column.
+func ValidateExactColumns(db *sqlx.DB, expected map[string][]string) error {
+	tables := make([]string, 0, len(expected))
+	for table := range expected {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		exists, err := tableExists(db, table)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return &ValidationError{Err: ErrTableMissing, Table: table}
+		}
+
+		columns, err := GetTableColumns(db, table)
+		if err != nil {
+			return err
+		}
+		actual := make([]string, len(columns))
+		for i, column := range columns {
+			actual[i] = column.Name
+		}
+		want := expected[table]
+
+		for _, name := range want {
+			if !containsFold(actual, name) {
+				return &ValidationError{Err: ErrColumnMissing, Table: table, Column: name}
+			}
+		}
+		for _, name := range actual {
+			if !containsFold(want, name) {
+				return fmt.Errorf("table %s: unexpected column %s", table, name)
+			}
+		}
+		for i := range want {
+			if !strings.EqualFold(actual[i], want[i]) {
+				return fmt.Errorf("table %s: expected column %s at position %d, got %s", table, want[i], i+1, actual[i])
+			}
+		}
+	}
+
+	return nil
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func IndexExists(db *sqlx.DB, name string) (bool, error) {
+	var count int
+	err := db.Get(&count, `SELECT COUNT(1) FROM sqlite_master WHERE type = 'index' AND name = ? COLLATE NOCASE`, name)
+	if err != nil {
+		return false, fmt.Errorf("query index %s: %w", name, err)
+	}
+
+	return count > 0, nil
+}
+
+func validateIndex(db *sqlx.DB, expected Index) error {
+	if strings.TrimSpace(expected.Name) == "" {
+		return errors.New("index name is required")
+	}
+
+	var table string
+	err := db.Get(&table, `SELECT tbl_name FROM sqlite_master WHERE type = 'index' AND name = ? COLLATE NOCASE`, expected.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &ValidationError{Err: ErrIndexMissing, Table: expected.Table, Name: expected.Name}
+		}
+		return fmt.Errorf("query index %s: %w", expected.Name, err)
+	}
+	if expected.Table != "" && !strings.EqualFold(table, expected.Table) {
+		return fmt.Errorf("index %s: expected table %s, got %s", expected.Name, expected.Table, table)
+	}
+
+	var unique bool
+	err = db.Get(&unique, `SELECT "unique" FROM pragma_index_list(?) WHERE name = ? COLLATE NOCASE`, table, expected.Name)
+	if err != nil {
+		return fmt.Errorf("query index list for %s: %w", table, err)
+	}
+	if unique != expected.Unique {
+		return fmt.Errorf("index %s: expected unique=%t, got unique=%t", expected.Name, expected.Unique, unique)
+	}
+
+	if len(expected.Columns) == 0 {
+		return nil
+	}
+
+	var columns []string
+	err = db.Select(&columns, `SELECT name FROM pragma_index_info(?) ORDER BY seqno`, expected.Name)
+	if err != nil {
+		return fmt.Errorf("query index info for %s: %w", expected.Name, err)
+	}
+	if !equalFoldSlices(columns, expected.Columns) {
+		return fmt.Errorf("index %s: expected columns (%s), got (%s)", expected.Name, strings.Join(expected.Columns, ", "), strings.Join(columns, ", "))
+	}
+
+	return nil
+}
+
+type foreignKeyRow struct {
+	Table    string         `db:"table"`
+	From     string         `db:"from"`
+	To       sql.NullString `db:"to"`
+	OnUpdate string         `db:"on_update"`
+	OnDelete string         `db:"on_delete"`
+}
+
+func validateForeignKey(db *sqlx.DB, expected ForeignKey) error {
+	if strings.TrimSpace(expected.Table) == "" || strings.TrimSpace(expected.Column) == "" || strings.TrimSpace(expected.ParentTable) == "" {
+		return errors.New("foreign key table, column and parent table are required")
+	}
+
+	var rows []foreignKeyRow
+	err := db.Select(&rows, `SELECT "table", "from", "to", on_update, on_delete FROM pragma_foreign_key_list(?)`, expected.Table)
+	if err != nil {
+		return fmt.Errorf("query foreign keys for %s: %w", expected.Table, err)
+	}
+
+	for _, row := range rows {
+		if !strings.EqualFold(row.From, expected.Column) || !strings.EqualFold(row.Table, expected.ParentTable) {
+			continue
+		}
+
+		if expected.ParentColumn != "" {
+			parentColumn := row.To.String
+			if !row.To.Valid {
+				// REFERENCES parent without a column list targets the parent's primary key.
+				err := db.Get(&parentColumn, `SELECT name FROM pragma_table_info(?) WHERE pk = 1`, row.Table)
+				if err != nil {
+					return fmt.Errorf("foreign key %s: resolve parent primary key: %w", expected, err)
+				}
+			}
+			if !strings.EqualFold(parentColumn, expected.ParentColumn) {
+				return fmt.Errorf("foreign key %s: references column %s", expected, parentColumn)
+			}
+		}
+		if expected.OnDelete != "" && !strings.EqualFold(row.OnDelete, expected.OnDelete) {
+			return fmt.Errorf("foreign key %s: expected ON DELETE %s, got %s", expected, strings.ToUpper(expected.OnDelete), row.OnDelete)
+		}
+		if expected.OnUpdate != "" && !strings.EqualFold(row.OnUpdate, expected.OnUpdate) {
+			return fmt.Errorf("foreign key %s: expected ON UPDATE %s, got %s", expected, strings.ToUpper(expected.OnUpdate), row.OnUpdate)
+		}
+
+		return nil
+	}
+
+	return &ValidationError{Err: ErrForeignKeyMissing, Table: expected.Table, Column: expected.Column, Name: expected.String()}
+}
+
+func validateObject(db *sqlx.DB, objectType, name, table, expectedSQL string) error {
+	var object schemaObject
+	err := db.Get(&object, `SELECT name, tbl_name, sql FROM sqlite_master WHERE type = ? AND name = ? COLLATE NOCASE`, objectType, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s %s is missing", objectType, name)
+		}
+		return fmt.Errorf("query %s %s: %w", objectType, name, err)
+	}
+	if table != "" && !strings.EqualFold(object.Table, table) {
+		return fmt.Errorf("%s %s: expected table %s, got %s", objectType, name, table, object.Table)
+	}
+	if expectedSQL != "" && canonicalSQL(object.SQL) != canonicalSQL(expectedSQL) {
+		return fmt.Errorf("%s %s: SQL differs from expected definition", objectType, name)
+	}
+
+	return nil
+}
+
+// ValidateCreateSQL compares the stored CREATE TABLE statement of table with
+// expectedSQL token by token, ignoring whitespace, comments, identifier
+// quoting and the case of keywords and identifiers, but not of string
+// literals. Any structural difference, such as a constraint, a default or
+// the column order, fails with an error showing where the statements first
+// diverge.
+func ValidateCreateSQL(db *sqlx.DB, table, expectedSQL string) error {
+	exists, err := tableExists(db, table)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &ValidationError{Err: ErrTableMissing, Table: table}
+	}
+	actualSQL, err := tableSQL(db, table)
+	if err != nil {
+		return err
+	}
+
+	expected := canonicalTokens(strings.TrimSuffix(strings.TrimSpace(expectedSQL), ";"))
+	actual := canonicalTokens(actualSQL)
+	i := 0
+	for i < len(expected) && i < len(actual) && expected[i] == actual[i] {
+		i++
+	}
+	if i == len(expected) && i == len(actual) {
+		return nil
+	}
+
+	return fmt.Errorf("table %s: CREATE SQL differs at token %d: expected %q, got %q", table, i+1, tokenContext(expected, i), tokenContext(actual, i))
+}
+
+// tokenContext returns a few tokens around position i for error messages.
+func tokenContext(tokens []string, i int) string {
+	start, end := max(i-3, 0), min(i+4, len(tokens))
+	text := strings.Join(tokens[start:end], " ")
+	if start > 0 {
+		text = "... " + text
+	}
+	if end < len(tokens) {
+		text += " ..."
+	}
+	if i >= len(tokens) {
+		text += " <end>"
+	}
+
+	return text
+}
+
+func equalFoldSlices(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
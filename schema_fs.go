@@ -0,0 +1,250 @@
+package sqlite_base
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pressly/goose/v3"
+)
+
+// InitResult reports what InitSchema did. Created lists the tables it
+// created and Skipped the tables that already existed, which are left
+// untouched; Validated lists the tables of Schema.Columns and Schema.Tables
+// that passed validation afterwards. All three are sorted by name.
+type InitResult struct {
+	Created   []string
+	Validated []string
+	Skipped   []string
+}
+
+// SchemaFilesTable is the table in which InitSchemaFromFS and InitSchema
+// record the schema files they have applied.
+const SchemaFilesTable = "schema_files"
+
+// InitSchemaFromFS applies the SQL files in fsys matching glob, such as
+// "schema/*.sql" in an embed.FS. Files run in name order and each is split
+// into statements, which may span lines and include comments and trigger
+// bodies. Every file applied is recorded by name in SchemaFilesTable and is
+// not run again, so calling this on every startup applies each file once,
+// including files added later; an applied file must not be edited. All
+// pending files run in one transaction.
+func InitSchemaFromFS(db *sqlx.DB, fsys fs.FS, glob string) error {
+	_, err := InitSchema(db, fsys, glob, Schema{})
+	return err
+}
+
+// InitSchema applies the pending schema files like InitSchemaFromFS, then
+// creates the tables of schema.Tables that do not exist yet from their
+// definitions, and finally validates schema, reporting which tables were
+// created, validated and skipped. fsys may be nil to create the schema from
+// schema.Tables alone.
+func InitSchema(db *sqlx.DB, fsys fs.FS, glob string, schema Schema) (InitResult, error) {
+	var result InitResult
+
+	var files []string
+	if fsys != nil {
+		var err error
+		files, err = fs.Glob(fsys, glob)
+		if err != nil {
+			return result, fmt.Errorf("match schema files: %w", err)
+		}
+		if len(files) == 0 {
+			return result, fmt.Errorf("no schema files match %s", glob)
+		}
+		sort.Strings(files)
+	}
+
+	tables, err := schemaTables(db)
+	if err != nil {
+		return result, err
+	}
+	existing := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		existing[strings.ToLower(table)] = true
+	}
+	var creates []string
+	for _, table := range schema.Tables {
+		if existing[strings.ToLower(table.Name)] {
+			continue
+		}
+		// The schema files may create the table too when they run.
+		stmt, err := table.createSQL("CREATE TABLE IF NOT EXISTS")
+		if err != nil {
+			return result, err
+		}
+		creates = append(creates, stmt)
+	}
+
+	if len(files) > 0 || len(creates) > 0 {
+		err := WithTx(db, func(tx *sqlx.Tx) error {
+			if len(files) > 0 {
+				if err := applySchemaFiles(tx, fsys, files); err != nil {
+					return err
+				}
+			}
+			for _, stmt := range creates {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("create table: %w", err)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	after, err := schemaTables(db)
+	if err != nil {
+		return result, err
+	}
+	for _, table := range after {
+		if existing[strings.ToLower(table)] {
+			result.Skipped = append(result.Skipped, table)
+		} else {
+			result.Created = append(result.Created, table)
+		}
+	}
+
+	if err := ValidateSchema(db, schema); err != nil {
+		return result, err
+	}
+	validated := make(map[string]bool)
+	for table := range schema.Columns {
+		validated[table] = true
+	}
+	for _, table := range schema.Tables {
+		validated[table.Name] = true
+	}
+	for table := range validated {
+		result.Validated = append(result.Validated, table)
+	}
+	sort.Strings(result.Validated)
+
+	return result, nil
+}
+
+// applySchemaFiles runs the files SchemaFilesTable does not list yet and
+// records each of them there.
+func applySchemaFiles(tx *sqlx.Tx, fsys fs.FS, files []string) error {
+	pending, err := pendingSchemaFiles(tx, files)
+	if err != nil {
+		return err
+	}
+	for _, file := range pending {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("read schema file %s: %w", file, err)
+		}
+		for i, stmt := range splitStatements(string(content)) {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("schema file %s: statement %d: %w", file, i+1, err)
+			}
+		}
+		if _, err := tx.Exec(fmt.Sprintf("INSERT INTO %s (name) VALUES (?)", quoteIdent(SchemaFilesTable)), file); err != nil {
+			return fmt.Errorf("record schema file %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+// pendingSchemaFiles creates SchemaFilesTable if needed and returns the
+// files it does not list yet, in the order given.
+func pendingSchemaFiles(tx *sqlx.Tx, files []string) ([]string, error) {
+	stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name TEXT PRIMARY KEY, applied_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP)", quoteIdent(SchemaFilesTable))
+	if _, err := tx.Exec(stmt); err != nil {
+		return nil, fmt.Errorf("create %s: %w", SchemaFilesTable, err)
+	}
+
+	var names []string
+	if err := tx.Select(&names, "SELECT name FROM "+quoteIdent(SchemaFilesTable)); err != nil {
+		return nil, fmt.Errorf("read applied schema files: %w", err)
+	}
+	applied := make(map[string]bool, len(names))
+	for _, name := range names {
+		applied[name] = true
+	}
+
+	var pending []string
+	for _, file := range files {
+		if !applied[file] {
+			pending = append(pending, file)
+		}
+	}
+
+	return pending, nil
+}
+
+// schemaTables returns ListTables without the bookkeeping tables of goose
+// and InitSchema.
+func schemaTables(db *sqlx.DB) ([]string, error) {
+	tables, err := ListTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := tables[:0]
+	for _, table := range tables {
+		if strings.EqualFold(table, goose.TableName()) || strings.EqualFold(table, SchemaFilesTable) {
+			continue
+		}
+		filtered = append(filtered, table)
+	}
+
+	return filtered, nil
+}
+
+// splitStatements splits a SQL script on semicolons outside string literals,
+// comments and CREATE TRIGGER bodies.
+func splitStatements(script string) []string {
+	var stmts []string
+	start := -1
+	trigger := false
+	depth := 0
+	for _, token := range sqlTokens(script) {
+		if start < 0 {
+			if isSemicolon(token) {
+				continue
+			}
+			start = token.start
+			trigger = false
+			depth = 0
+		}
+		if token.word {
+			switch strings.ToUpper(token.text) {
+			case "TRIGGER":
+				trigger = true
+			case "BEGIN":
+				if trigger {
+					depth++
+				}
+			case "CASE":
+				depth++
+			case "END":
+				if depth > 0 {
+					depth--
+				}
+			}
+		}
+		if isSemicolon(token) && depth == 0 {
+			stmts = append(stmts, strings.TrimSpace(script[start:token.start]))
+			start = -1
+		}
+	}
+	if start >= 0 {
+		if stmt := strings.TrimSpace(script[start:]); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	return stmts
+}
+
+func isSemicolon(token sqlToken) bool {
+	return !token.word && !token.literal && token.text == ";"
+}
@@ -0,0 +1,142 @@
+package sqlite_base
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitStatements(t *testing.T) {
+	t.Parallel()
+
+	script := `-- users; not a statement
+CREATE TABLE users (id INTEGER PRIMARY KEY, note TEXT DEFAULT 'a;b');
+/* block; comment */
+CREATE TRIGGER users_touch AFTER UPDATE ON users BEGIN
+    UPDATE users SET note = CASE WHEN NEW.note IS NULL THEN 'x' ELSE NEW.note END WHERE id = NEW.id;
+END;
+;
+INSERT INTO users (note) VALUES ('last')`
+
+	want := []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, note TEXT DEFAULT 'a;b')",
+		"CREATE TRIGGER users_touch AFTER UPDATE ON users BEGIN\n    UPDATE users SET note = CASE WHEN NEW.note IS NULL THEN 'x' ELSE NEW.note END WHERE id = NEW.id;\nEND",
+		"INSERT INTO users (note) VALUES ('last')",
+	}
+	if got := splitStatements(script); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected statements:\n%q", got)
+	}
+}
+
+func TestInitSchemaFromFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"schema/02_posts.sql": {Data: []byte("CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id));\nCREATE INDEX idx_posts_user ON posts (user_id);")},
+		"schema/01_users.sql": {Data: []byte("-- users first\nCREATE TABLE users (id INTEGER PRIMARY KEY);")},
+		"schema/readme.txt":   {Data: []byte("not sql")},
+	}
+
+	db := newTestDB(t)
+	for i := 0; i < 2; i++ {
+		if err := InitSchemaFromFS(db, fsys, "schema/*.sql"); err != nil {
+			t.Fatalf("init schema run %d failed: %v", i+1, err)
+		}
+	}
+
+	tables, err := ListTables(db)
+	if err != nil {
+		t.Fatalf("list tables failed: %v", err)
+	}
+	if !reflect.DeepEqual(tables, []string{"posts", "schema_files", "users"}) {
+		t.Fatalf("unexpected tables: %v", tables)
+	}
+
+	fsys["schema/03_tags.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE tags (id INTEGER PRIMARY KEY);")}
+	if err := InitSchemaFromFS(db, fsys, "schema/*.sql"); err != nil {
+		t.Fatalf("init schema with a new file failed: %v", err)
+	}
+	var applied []string
+	if err := db.Select(&applied, "SELECT name FROM schema_files ORDER BY name"); err != nil {
+		t.Fatalf("read applied files failed: %v", err)
+	}
+	if !reflect.DeepEqual(applied, []string{"schema/01_users.sql", "schema/02_posts.sql", "schema/03_tags.sql"}) {
+		t.Fatalf("unexpected applied files: %v", applied)
+	}
+
+	migrated := newTestDB(t, "CREATE TABLE goose_db_version (id INTEGER PRIMARY KEY, version_id INTEGER, is_applied INTEGER)")
+	if err := InitSchemaFromFS(migrated, fsys, "schema/*.sql"); err != nil {
+		t.Fatalf("init schema after migrations failed: %v", err)
+	}
+	if exists, err := tableExists(migrated, "tags"); err != nil || !exists {
+		t.Fatalf("expected schema files to run next to the goose table, exists=%v err=%v", exists, err)
+	}
+
+	broken := fstest.MapFS{"schema/01.sql": {Data: []byte("CREATE TABLE a (id INTEGER);\nCREATE TABLE oops (;")}}
+	fresh := newTestDB(t)
+	if err := InitSchemaFromFS(fresh, broken, "schema/*.sql"); err == nil {
+		t.Fatal("expected broken schema to fail")
+	}
+	if tables, _ := ListTables(fresh); len(tables) != 0 {
+		t.Fatalf("expected rollback of partial schema, got %v", tables)
+	}
+}
+
+func TestInitSchema_ReportsResult(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"schema/01.sql": {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);\nCREATE TABLE posts (id INTEGER PRIMARY KEY);")},
+	}
+	schema := Schema{Columns: map[string]map[string]string{"users": {"email": "TEXT"}}}
+
+	db := newTestDB(t)
+	result, err := InitSchema(db, fsys, "schema/*.sql", schema)
+	if err != nil {
+		t.Fatalf("init schema failed: %v", err)
+	}
+	want := InitResult{Created: []string{"posts", "users"}, Validated: []string{"users"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("unexpected first result: %+v", result)
+	}
+
+	result, err = InitSchema(db, fsys, "schema/*.sql", schema)
+	if err != nil {
+		t.Fatalf("second init schema failed: %v", err)
+	}
+	want = InitResult{Validated: []string{"users"}, Skipped: []string{"posts", "users"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("unexpected second result: %+v", result)
+	}
+
+	schema.Columns["users"]["name"] = "TEXT"
+	result, err = InitSchema(db, fsys, "schema/*.sql", schema)
+	if err == nil || len(result.Validated) != 0 || len(result.Skipped) != 2 {
+		t.Fatalf("expected validation failure after skipping, got %+v, %v", result, err)
+	}
+}
+
+func TestInitSchema_CreatesTableDefinitions(t *testing.T) {
+	t.Parallel()
+
+	schema := Schema{Tables: []TableDefinition{
+		{Name: "users", Columns: []ColumnSpec{
+			{Name: "id", Type: "INTEGER", PrimaryKey: true},
+			{Name: "email", Type: "TEXT", NotNull: true, Unique: true},
+		}},
+		{Name: "posts", Columns: []ColumnSpec{
+			{Name: "id", Type: "INTEGER", PrimaryKey: true},
+			{Name: "title", Type: "TEXT"},
+		}},
+	}}
+
+	db := newTestDB(t, "CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT)")
+	result, err := InitSchema(db, nil, "", schema)
+	if err != nil {
+		t.Fatalf("init schema failed: %v", err)
+	}
+	want := InitResult{Created: []string{"users"}, Validated: []string{"posts", "users"}, Skipped: []string{"posts"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
@@ -0,0 +1,221 @@
+package sqlite_base
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type tableNamer interface {
+	TableName() string
+}
+
+type strictTable interface {
+	Strict() bool
+}
+
+type structColumn struct {
+	index      []int
+	name       string
+	sqlType    string
+	primaryKey bool
+	notNull    bool
+	softDelete bool
+}
+
+// SchemaFromStruct derives a CREATE TABLE statement and the matching
+// Schema.Columns entry from a struct. Column names come from `db` tags
+// (falling back to the lowercased field name) and the `sqlite` tag accepts
+// comma-separated options: pk, notnull, softdelete (see Delete) and
+// type=<SQL type>, which fields of custom driver.Valuer or sql.Scanner types
+// need. A field tagged `db:"-"` or `sqlite:"-"` is skipped. The table name
+// comes from a TableName() method when present, otherwise the snake_cased
+// type name. A Strict() method returning true emits a STRICT table, storing
+// time.Time columns as TEXT since STRICT tables only accept INT, INTEGER,
+// REAL, TEXT, BLOB and ANY.
+func SchemaFromStruct(v interface{}) (createSQL string, expected map[string]string, err error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", nil, errors.New("schema source must be a struct")
+	}
+
+	table := toSnakeCase(t.Name())
+	if namer, ok := v.(tableNamer); ok {
+		table = namer.TableName()
+	}
+	if strings.TrimSpace(table) == "" {
+		return "", nil, errors.New("table name is required")
+	}
+
+	columns, err := structColumns(t)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(columns) == 0 {
+		return "", nil, fmt.Errorf("struct %s has no columns", t.Name())
+	}
+
+	strict := false
+	if s, ok := v.(strictTable); ok {
+		strict = s.Strict()
+	}
+
+	defs := make([]string, 0, len(columns))
+	expected = make(map[string]string, len(columns))
+	for _, column := range columns {
+		if column.sqlType == "" {
+			return "", nil, fmt.Errorf("column %s: cannot derive a column type, add a type= sqlite tag option", column.name)
+		}
+		if strict && column.sqlType == "DATETIME" {
+			column.sqlType = "TEXT"
+		}
+		if strict && !strictTypes[strings.ToUpper(column.sqlType)] {
+			return "", nil, fmt.Errorf("column %s: type %s is not allowed in a STRICT table", column.name, column.sqlType)
+		}
+		def := quoteIdent(column.name) + " " + column.sqlType
+		if column.primaryKey {
+			def += " PRIMARY KEY"
+		}
+		if column.notNull {
+			def += " NOT NULL"
+		}
+		defs = append(defs, def)
+		expected[column.name] = column.sqlType
+	}
+
+	createSQL = fmt.Sprintf("CREATE TABLE %s (\n    %s\n)", quoteIdent(table), strings.Join(defs, ",\n    "))
+	if strict {
+		createSQL += " STRICT"
+	}
+
+	return createSQL, expected, nil
+}
+
+func structColumns(t reflect.Type) ([]structColumn, error) {
+	var columns []structColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		dbTag := field.Tag.Get("db")
+		sqliteTag := field.Tag.Get("sqlite")
+		if dbTag == "-" || sqliteTag == "-" {
+			continue
+		}
+		if field.Anonymous && dbTag == "" && field.Type.Kind() == reflect.Struct {
+			embedded, err := structColumns(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			for _, column := range embedded {
+				column.index = append([]int{i}, column.index...)
+				columns = append(columns, column)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		column := structColumn{index: field.Index, name: strings.ToLower(field.Name)}
+		if name, _, _ := strings.Cut(dbTag, ","); name != "" {
+			column.name = name
+		}
+		for _, opt := range strings.Split(sqliteTag, ",") {
+			opt = strings.TrimSpace(opt)
+			switch {
+			case opt == "":
+			case strings.EqualFold(opt, "pk"):
+				column.primaryKey = true
+			case strings.EqualFold(opt, "notnull"):
+				column.notNull = true
+			case strings.EqualFold(opt, "softdelete"):
+				column.softDelete = true
+			case strings.HasPrefix(strings.ToLower(opt), "type="):
+				column.sqlType = strings.TrimSpace(opt[len("type="):])
+			default:
+				return nil, fmt.Errorf("field %s: unknown sqlite tag option %q", field.Name, opt)
+			}
+		}
+		if column.softDelete && field.Type != reflect.PointerTo(timeType) && field.Type != reflect.TypeOf(sql.NullTime{}) {
+			// A live row must hold NULL, which a plain time.Time cannot.
+			return nil, fmt.Errorf("field %s: softdelete needs a *time.Time or sql.NullTime field, got %s", field.Name, field.Type)
+		}
+		if column.sqlType == "" {
+			sqlType, ok := sqlTypeOf(field.Type)
+			if !ok && !implementsValuerOrScanner(field.Type) {
+				return nil, fmt.Errorf("field %s: cannot map Go type %s to a column type", field.Name, field.Type)
+			}
+			column.sqlType = sqlType
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// implementsValuerOrScanner reports whether values of t can be passed to and
+// scanned from the driver even though sqlTypeOf has no column type for them.
+// Such fields work with the CRUD helpers but need a type= option in
+// SchemaFromStruct.
+func implementsValuerOrScanner(t reflect.Type) bool {
+	return t.Implements(valuerType) || reflect.PointerTo(t).Implements(scannerType)
+}
+
+func sqlTypeOf(t reflect.Type) (string, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return "DATETIME", true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "INTEGER", true
+	case reflect.Float32, reflect.Float64:
+		return "REAL", true
+	case reflect.String:
+		return "TEXT", true
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "BLOB", true
+		}
+	case reflect.Struct:
+		// sql.NullString and friends wrap their value in a field named after its type.
+		if strings.HasPrefix(t.Name(), "Null") && t.NumField() == 2 {
+			return sqlTypeOf(t.Field(0).Type)
+		}
+	}
+
+	return "", false
+}
+
+func toSnakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package sqlite_base
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type accountRecord struct {
+	ID        int64          `db:"id" sqlite:"pk"`
+	Email     string         `db:"email" sqlite:"notnull,type=VARCHAR(255)"`
+	Nickname  sql.NullString `db:"nickname"`
+	Balance   float64        `db:"balance" sqlite:"notnull"`
+	CreatedAt time.Time      `db:"created_at" sqlite:"notnull"`
+	Cached    string         `db:"-"`
+}
+
+func (accountRecord) TableName() string { return "accounts" }
+
+func TestSchemaFromStruct(t *testing.T) {
+	t.Parallel()
+
+	createSQL, expected, err := SchemaFromStruct(accountRecord{})
+	if err != nil {
+		t.Fatalf("schema from struct failed: %v", err)
+	}
+
+	wantSQL := "CREATE TABLE \"accounts\" (\n" +
+		"    \"id\" INTEGER PRIMARY KEY,\n" +
+		"    \"email\" VARCHAR(255) NOT NULL,\n" +
+		"    \"nickname\" TEXT,\n" +
+		"    \"balance\" REAL NOT NULL,\n" +
+		"    \"created_at\" DATETIME NOT NULL\n" +
+		")"
+	if createSQL != wantSQL {
+		t.Fatalf("unexpected create sql:\n%s", createSQL)
+	}
+
+	wantColumns := map[string]string{
+		"id":         "INTEGER",
+		"email":      "VARCHAR(255)",
+		"nickname":   "TEXT",
+		"balance":    "REAL",
+		"created_at": "DATETIME",
+	}
+	if !reflect.DeepEqual(expected, wantColumns) {
+		t.Fatalf("expected columns %v, got %v", wantColumns, expected)
+	}
+
+	db := newTestDB(t, createSQL)
+	if err := ValidateSchema(db, Schema{Columns: map[string]map[string]string{"accounts": expected}}); err != nil {
+		t.Fatalf("generated schema failed validation: %v", err)
+	}
+}
+
+func TestSchemaFromStruct_DefaultTableName(t *testing.T) {
+	t.Parallel()
+
+	type OrderItem struct {
+		ID  int `sqlite:"pk"`
+		Qty int
+	}
+
+	createSQL, _, err := SchemaFromStruct(&OrderItem{})
+	if err != nil {
+		t.Fatalf("schema from struct failed: %v", err)
+	}
+	want := "CREATE TABLE \"order_item\" (\n    \"id\" INTEGER PRIMARY KEY,\n    \"qty\" INTEGER\n)"
+	if createSQL != want {
+		t.Fatalf("unexpected create sql:\n%s", createSQL)
+	}
+}
+
+func TestSchemaFromStruct_RejectsInvalidInput(t *testing.T) {
+	t.Parallel()
+
+	if _, _, err := SchemaFromStruct(42); err == nil {
+		t.Fatal("expected error for non-struct input")
+	}
+
+	type badTag struct {
+		ID int `sqlite:"primary"`
+	}
+	if _, _, err := SchemaFromStruct(badTag{}); err == nil {
+		t.Fatal("expected error for unknown tag option")
+	}
+
+	type badType struct {
+		Tags map[string]string
+	}
+	if _, _, err := SchemaFromStruct(badType{}); err == nil {
+		t.Fatal("expected error for unmappable field type")
+	}
+
+	type untypedValuer struct {
+		Tags tagList
+	}
+	if _, _, err := SchemaFromStruct(untypedValuer{}); err == nil {
+		t.Fatal("expected error for a Valuer field without a type option")
+	}
+	type typedValuer struct {
+		Tags tagList `sqlite:"type=TEXT"`
+	}
+	if _, _, err := SchemaFromStruct(typedValuer{}); err != nil {
+		t.Fatalf("schema from struct failed: %v", err)
+	}
+}
+
+type eventRecord struct {
+	ID        int64     `db:"id" sqlite:"pk"`
+	Payload   []byte    `db:"payload"`
+	CreatedAt time.Time `db:"created_at" sqlite:"notnull"`
+}
+
+func (eventRecord) Strict() bool { return true }
+
+func TestSchemaFromStruct_Strict(t *testing.T) {
+	t.Parallel()
+
+	createSQL, expected, err := SchemaFromStruct(eventRecord{})
+	if err != nil {
+		t.Fatalf("schema from struct failed: %v", err)
+	}
+
+	db := newTestDB(t, createSQL)
+	err = ValidateSchema(db, Schema{
+		Columns:      map[string]map[string]string{"event_record": expected},
+		StrictTables: []string{"event_record"},
+	})
+	if err != nil {
+		t.Fatalf("validate strict schema failed: %v", err)
+	}
+	if expected["created_at"] != "TEXT" {
+		t.Fatalf("expected time column stored as TEXT, got %s", expected["created_at"])
+	}
+
+	if _, _, err := SchemaFromStruct(struct {
+		eventRecord
+		Name string `db:"name" sqlite:"type=VARCHAR(10)"`
+	}{}); err == nil {
+		t.Fatal("expected VARCHAR column in STRICT table to fail")
+	}
+}
@@ -0,0 +1,416 @@
+package sqlite_base
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndexExists(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL)",
+		"CREATE UNIQUE INDEX idx_users_email ON users (email)",
+	)
+
+	exists, err := IndexExists(db, "idx_users_email")
+	if err != nil {
+		t.Fatalf("index exists failed: %v", err)
+	}
+	if !exists {
+		t.Fatal("expected idx_users_email to exist")
+	}
+
+	exists, err = IndexExists(db, "idx_missing")
+	if err != nil {
+		t.Fatalf("index exists failed: %v", err)
+	}
+	if exists {
+		t.Fatal("expected idx_missing to not exist")
+	}
+}
+
+func TestValidateSchema_Indexes(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT NOT NULL)",
+		"CREATE UNIQUE INDEX idx_users_email ON users (email)",
+		"CREATE INDEX idx_users_name ON users (name, email)",
+	)
+
+	valid := Schema{Indexes: []Index{
+		{Name: "idx_users_email", Table: "users", Columns: []string{"email"}, Unique: true},
+		{Name: "idx_users_name", Table: "users", Columns: []string{"name", "email"}},
+	}}
+	if err := ValidateSchema(db, valid); err != nil {
+		t.Fatalf("expected valid schema, got: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		index Index
+		want  string
+	}{
+		{"missing", Index{Name: "idx_users_role", Table: "users"}, "idx_users_role is missing"},
+		{"table", Index{Name: "idx_users_email", Table: "accounts", Unique: true}, "expected table accounts"},
+		{"unique", Index{Name: "idx_users_name", Table: "users"}, ""},
+		{"not unique", Index{Name: "idx_users_name", Table: "users", Unique: true}, "expected unique=true"},
+		{"columns", Index{Name: "idx_users_name", Table: "users", Columns: []string{"email", "name"}}, "expected columns (email, name)"},
+	}
+	for _, tt := range tests {
+		err := ValidateSchema(db, Schema{Indexes: []Index{tt.index}})
+		if tt.want == "" {
+			if err != nil {
+				t.Fatalf("%s: expected no error, got: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got: %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestOpen_ValidatesSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "app.sqlite")
+
+	_, err := Open(Config{
+		Path:         dbPath,
+		MigrationDir: "examples/migrations",
+		MigrationFS:  embedMigrations,
+		Schema: Schema{Indexes: []Index{
+			{Name: "idx_users_email", Table: "users", Columns: []string{"email"}, Unique: true},
+			{Name: "idx_users_role", Table: "users", Columns: []string{"role"}},
+		}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "idx_users_role is missing") {
+		t.Fatalf("expected missing index error, got: %v", err)
+	}
+}
+
+func TestValidateSchema_ForeignKeys(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY, code TEXT UNIQUE)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER REFERENCES customers ON DELETE CASCADE, customer_code TEXT REFERENCES customers (code), note TEXT)",
+	)
+
+	valid := Schema{ForeignKeys: []ForeignKey{
+		{Table: "orders", Column: "customer_id", ParentTable: "customers", ParentColumn: "id", OnDelete: "cascade", OnUpdate: "NO ACTION"},
+		{Table: "orders", Column: "customer_code", ParentTable: "customers", ParentColumn: "code"},
+	}}
+	if err := ValidateSchema(db, valid); err != nil {
+		t.Fatalf("expected valid schema, got: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		fk   ForeignKey
+		want string
+	}{
+		{"missing", ForeignKey{Table: "orders", Column: "note", ParentTable: "customers", ParentColumn: "id"}, "orders.note -> customers.id is missing"},
+		{"parent column", ForeignKey{Table: "orders", Column: "customer_code", ParentTable: "customers", ParentColumn: "id"}, "references column code"},
+		{"on delete", ForeignKey{Table: "orders", Column: "customer_code", ParentTable: "customers", OnDelete: "CASCADE"}, "expected ON DELETE CASCADE, got NO ACTION"},
+	}
+	for _, tt := range tests {
+		err := ValidateSchema(db, Schema{ForeignKeys: []ForeignKey{tt.fk}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got: %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestValidateSchema_Columns(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, extra BLOB)")
+
+	valid := Schema{Columns: map[string]map[string]string{
+		"users": {"id": "INTEGER", "NAME": "text"},
+	}}
+	if err := ValidateSchema(db, valid); err != nil {
+		t.Fatalf("expected valid schema, got: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		columns map[string]map[string]string
+		want    string
+	}{
+		{"table", map[string]map[string]string{"accounts": {"id": "INTEGER"}}, "table accounts is missing"},
+		{"column", map[string]map[string]string{"users": {"email": "TEXT"}}, "table users: column email is missing"},
+		{"type", map[string]map[string]string{"users": {"name": "INTEGER"}}, "column name expected type INTEGER, got TEXT"},
+	}
+	for _, tt := range tests {
+		err := ValidateSchema(db, Schema{Columns: tt.columns})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got: %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestValidateExactColumns(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT)")
+
+	if err := ValidateExactColumns(db, map[string][]string{"users": {"ID", "name", "email"}}); err != nil {
+		t.Fatalf("expected exact match, got: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		columns []string
+		want    string
+	}{
+		{"extra", []string{"id", "name"}, "unexpected column email"},
+		{"missing", []string{"id", "name", "email", "role"}, "column role is missing"},
+		{"reorder", []string{"id", "email", "name"}, "expected column email at position 2, got name"},
+	}
+	for _, tt := range tests {
+		err := ValidateExactColumns(db, map[string][]string{"users": tt.columns})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got: %v", tt.name, tt.want, err)
+		}
+	}
+
+	if err := ValidateExactColumns(db, map[string][]string{"accounts": {"id"}}); err == nil {
+		t.Fatal("expected error for missing table")
+	}
+}
+
+func TestValidateSchema_ViewsAndTriggers(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, active INTEGER NOT NULL)",
+		"CREATE TABLE audit_log (id INTEGER PRIMARY KEY, user_id INTEGER)",
+		"CREATE VIEW active_users AS SELECT id FROM users WHERE active = 1",
+		"CREATE TRIGGER audit_users AFTER UPDATE ON users BEGIN INSERT INTO audit_log (user_id) VALUES (NEW.id); END",
+	)
+
+	valid := Schema{
+		Views:    []View{{Name: "active_users", SQL: "create view active_users as\n  select id from users where active = 1"}},
+		Triggers: []Trigger{{Name: "audit_users", Table: "users"}},
+	}
+	if err := ValidateSchema(db, valid); err != nil {
+		t.Fatalf("expected valid schema, got: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		schema Schema
+		want   string
+	}{
+		{"missing view", Schema{Views: []View{{Name: "inactive_users"}}}, "view inactive_users is missing"},
+		{"view sql", Schema{Views: []View{{Name: "active_users", SQL: "CREATE VIEW active_users AS SELECT id FROM users"}}}, "view active_users: SQL differs"},
+		{"missing trigger", Schema{Triggers: []Trigger{{Name: "audit_deletes"}}}, "trigger audit_deletes is missing"},
+		{"trigger table", Schema{Triggers: []Trigger{{Name: "audit_users", Table: "audit_log"}}}, "expected table audit_log"},
+	}
+	for _, tt := range tests {
+		err := ValidateSchema(db, tt.schema)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got: %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestValidateSchema_StrictTables(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE strict_items (id INTEGER PRIMARY KEY, name TEXT NOT NULL) STRICT",
+		"CREATE TABLE loose_items (id INTEGER PRIMARY KEY, name VARCHAR(20))",
+	)
+
+	if err := ValidateSchema(db, Schema{StrictTables: []string{"strict_items"}}); err != nil {
+		t.Fatalf("validate strict table failed: %v", err)
+	}
+	err := ValidateSchema(db, Schema{StrictTables: []string{"loose_items"}})
+	if err == nil || err.Error() != "table loose_items is not STRICT" {
+		t.Fatalf("expected not STRICT error, got %v", err)
+	}
+	err = ValidateSchema(db, Schema{Columns: map[string]map[string]string{"strict_items": {"name": "VARCHAR(20)"}}})
+	if err == nil || !strings.Contains(err.Error(), "not allowed in a STRICT table") {
+		t.Fatalf("expected strict type error, got %v", err)
+	}
+}
+
+func TestSnapshotExpectedColumns(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email VARCHAR(255) NOT NULL, data BLOB)",
+		"CREATE TABLE tags (name TEXT)",
+	)
+
+	snapshot, err := SnapshotExpectedColumns(db)
+	if err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	want := map[string]map[string]string{
+		"users": {"id": "INTEGER", "email": "VARCHAR(255)", "data": "BLOB"},
+		"tags":  {"name": "TEXT"},
+	}
+	if !reflect.DeepEqual(snapshot, want) {
+		t.Fatalf("unexpected snapshot: %v", snapshot)
+	}
+	if err := ValidateSchema(db, Schema{Columns: snapshot}); err != nil {
+		t.Fatalf("snapshot does not validate: %v", err)
+	}
+
+	if _, err := SnapshotExpectedColumns(db, "missing"); err == nil {
+		t.Fatal("expected missing table to fail")
+	}
+}
+
+func TestValidateSchema_WithoutRowidTables(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE codes (code TEXT PRIMARY KEY, label TEXT) WITHOUT ROWID",
+		"CREATE TABLE strict_codes (code TEXT PRIMARY KEY) STRICT, WITHOUT ROWID",
+		"CREATE TABLE plain (id INTEGER PRIMARY KEY)",
+	)
+
+	if err := ValidateSchema(db, Schema{WithoutRowidTables: []string{"codes", "strict_codes"}}); err != nil {
+		t.Fatalf("validate WITHOUT ROWID tables failed: %v", err)
+	}
+	err := ValidateSchema(db, Schema{WithoutRowidTables: []string{"plain"}})
+	if err == nil || err.Error() != "table plain is not WITHOUT ROWID" {
+		t.Fatalf("expected not WITHOUT ROWID error, got %v", err)
+	}
+}
+
+func TestGetTableColumns(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL, status TEXT DEFAULT 'active')")
+
+	columns, err := GetTableColumns(db, "users")
+	if err != nil {
+		t.Fatalf("get table columns failed: %v", err)
+	}
+	if len(columns) != 3 {
+		t.Fatalf("expected 3 columns, got %+v", columns)
+	}
+	if columns[0].Name != "id" || columns[0].PrimaryKey != 1 {
+		t.Fatalf("unexpected id column: %+v", columns[0])
+	}
+	if !columns[1].NotNull || columns[1].Type != "TEXT" {
+		t.Fatalf("unexpected email column: %+v", columns[1])
+	}
+	if !columns[2].DefaultValue.Valid || columns[2].DefaultValue.String != "'active'" {
+		t.Fatalf("unexpected status default: %+v", columns[2])
+	}
+
+	missing, err := GetTableColumns(db, "missing")
+	if err != nil || len(missing) != 0 {
+		t.Fatalf("expected no columns for a missing table, got %+v (err %v)", missing, err)
+	}
+}
+
+func TestValidateSchema_GeneratedColumns(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, `CREATE TABLE orders (
+		id INTEGER PRIMARY KEY,
+		price REAL,
+		qty INTEGER,
+		total REAL GENERATED ALWAYS AS (price * qty) VIRTUAL,
+		label TEXT AS ('order-' || id) STORED
+	)`)
+
+	columns, err := GetTableColumns(db, "orders")
+	if err != nil {
+		t.Fatalf("get columns failed: %v", err)
+	}
+	if len(columns) != 5 || columns[3].Generated() != "VIRTUAL" || columns[4].Generated() != "STORED" || columns[1].Generated() != "" {
+		t.Fatalf("unexpected columns: %+v", columns)
+	}
+
+	schema := Schema{
+		Columns: map[string]map[string]string{"orders": {"price": "REAL", "total": "REAL", "label": "TEXT"}},
+		GeneratedColumns: []GeneratedColumn{
+			{Table: "orders", Column: "total", Kind: "virtual"},
+			{Table: "orders", Column: "label"},
+		},
+	}
+	if err := ValidateSchema(db, schema); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	if err := ValidateExactColumns(db, map[string][]string{"orders": {"id", "price", "qty", "total", "label"}}); err != nil {
+		t.Fatalf("validate exact columns failed: %v", err)
+	}
+
+	for _, column := range []GeneratedColumn{
+		{Table: "orders", Column: "total", Kind: "STORED"},
+		{Table: "orders", Column: "price"},
+		{Table: "orders", Column: "missing"},
+	} {
+		if err := ValidateSchema(db, Schema{GeneratedColumns: []GeneratedColumn{column}}); err == nil {
+			t.Fatalf("expected %+v to fail validation", column)
+		}
+	}
+}
+
+func TestValidateCreateSQL(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, `CREATE TABLE users (
+		id INTEGER PRIMARY KEY,
+		email TEXT NOT NULL UNIQUE,
+		status TEXT DEFAULT 'Active'
+	)`)
+
+	matching := `create table "users" (id integer primary key, email text not null unique, status text default 'Active');`
+	if err := ValidateCreateSQL(db, "users", matching); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+		want     string
+	}{
+		{"constraint", "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE, status TEXT DEFAULT 'Active')", `at token 12: expected "... , email text unique , status text ...", got "... , email text not null unique , ..."`},
+		{"literal case", "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE, status TEXT DEFAULT 'active')", "'active'"},
+		{"extra column", "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE, status TEXT DEFAULT 'Active', name TEXT)", `expected "... text default 'Active' , name text )", got "... text default 'Active' )"`},
+	}
+	for _, tt := range tests {
+		err := ValidateCreateSQL(db, "users", tt.expected)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	if err := ValidateCreateSQL(db, "orders", "CREATE TABLE orders (id INTEGER)"); err == nil {
+		t.Fatal("expected missing table to fail")
+	}
+}
+
+func TestGetTableColumns_CachesVersion(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite"), Metrics: &recordingCollector{}})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	db.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+
+	if _, err := GetTableColumns(db, "items"); err != nil {
+		t.Fatalf("get table columns failed: %v", err)
+	}
+	before := Stats(db).Queries
+	if _, err := GetTableColumns(db, "items"); err != nil {
+		t.Fatalf("get table columns failed: %v", err)
+	}
+	if queries := Stats(db).Queries - before; queries != 1 {
+		t.Fatalf("expected a single query once the version is known, got %d", queries)
+	}
+}
@@ -0,0 +1,101 @@
+package sqlite_base
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type jsonTable struct {
+	Name        string           `json:"name"`
+	Columns     []jsonColumn     `json:"columns"`
+	Indexes     []jsonIndex      `json:"indexes"`
+	ForeignKeys []jsonForeignKey `json:"foreign_keys"`
+}
+
+type jsonColumn struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	NotNull    bool    `json:"notnull"`
+	PrimaryKey int     `json:"pk"`
+	Default    *string `json:"default"`
+}
+
+type jsonIndex struct {
+	Name    string   `json:"name" db:"name"`
+	Unique  bool     `json:"unique" db:"unique"`
+	Columns []string `json:"columns" db:"-"`
+}
+
+type jsonForeignKey struct {
+	Table    string `json:"table" db:"table"`
+	From     string `json:"from" db:"from"`
+	To       string `json:"to" db:"to"`
+	OnUpdate string `json:"on_update" db:"on_update"`
+	OnDelete string `json:"on_delete" db:"on_delete"`
+}
+
+// SchemaJSON describes every user table as indented JSON: its columns in
+// declaration order, its indexes by name and its foreign keys in declaration
+// order. Tables are sorted by name so the output diffs cleanly.
+func SchemaJSON(db *sqlx.DB) ([]byte, error) {
+	names, err := ListTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]jsonTable, 0, len(names))
+	for _, name := range names {
+		table, err := describeTable(db, name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+
+	out, err := json.MarshalIndent(tables, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode schema json: %w", err)
+	}
+
+	return out, nil
+}
+
+func describeTable(db *sqlx.DB, name string) (jsonTable, error) {
+	table := jsonTable{Name: name, Columns: []jsonColumn{}, Indexes: []jsonIndex{}, ForeignKeys: []jsonForeignKey{}}
+
+	columns, err := GetTableColumns(db, name)
+	if err != nil {
+		return table, err
+	}
+	for _, column := range columns {
+		c := jsonColumn{Name: column.Name, Type: column.Type, NotNull: column.NotNull, PrimaryKey: column.PrimaryKey}
+		if column.DefaultValue.Valid {
+			c.Default = &column.DefaultValue.String
+		}
+		table.Columns = append(table.Columns, c)
+	}
+
+	if err := db.Select(&table.Indexes, `SELECT name, "unique" FROM pragma_index_list(?) ORDER BY name`, name); err != nil {
+		return table, fmt.Errorf("query index list for %s: %w", name, err)
+	}
+	for i := range table.Indexes {
+		var indexColumns []sql.NullString
+		if err := db.Select(&indexColumns, `SELECT name FROM pragma_index_info(?) ORDER BY seqno`, table.Indexes[i].Name); err != nil {
+			return table, fmt.Errorf("query index info for %s: %w", table.Indexes[i].Name, err)
+		}
+		table.Indexes[i].Columns = make([]string, len(indexColumns))
+		for j, column := range indexColumns {
+			table.Indexes[i].Columns[j] = column.String
+		}
+	}
+
+	err = db.Select(&table.ForeignKeys, `SELECT "table", "from", COALESCE("to", '') AS "to", on_update, on_delete FROM pragma_foreign_key_list(?) ORDER BY id, seq`, name)
+	if err != nil {
+		return table, fmt.Errorf("query foreign keys for %s: %w", name, err)
+	}
+
+	return table, nil
+}
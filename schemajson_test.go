@@ -0,0 +1,52 @@
+package sqlite_base
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchemaJSON(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE, status TEXT DEFAULT 'active')",
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE, title TEXT)",
+		"CREATE INDEX idx_posts_user ON posts (user_id, title)",
+	)
+
+	out, err := SchemaJSON(db)
+	if err != nil {
+		t.Fatalf("schema json failed: %v", err)
+	}
+	again, err := SchemaJSON(db)
+	if err != nil || string(again) != string(out) {
+		t.Fatalf("expected stable output, err %v", err)
+	}
+
+	var tables []jsonTable
+	if err := json.Unmarshal(out, &tables); err != nil {
+		t.Fatalf("decode failed: %v\n%s", err, out)
+	}
+	if len(tables) != 2 || tables[0].Name != "posts" || tables[1].Name != "users" {
+		t.Fatalf("unexpected tables:\n%s", out)
+	}
+
+	posts := tables[0]
+	if len(posts.Columns) != 3 || posts.Columns[1].Name != "user_id" || !posts.Columns[1].NotNull {
+		t.Fatalf("unexpected posts columns: %+v", posts.Columns)
+	}
+	if len(posts.Indexes) != 1 || len(posts.Indexes[0].Columns) != 2 || posts.Indexes[0].Columns[1] != "title" {
+		t.Fatalf("unexpected posts indexes: %+v", posts.Indexes)
+	}
+	if len(posts.ForeignKeys) != 1 || posts.ForeignKeys[0].Table != "users" || posts.ForeignKeys[0].OnDelete != "CASCADE" {
+		t.Fatalf("unexpected posts foreign keys: %+v", posts.ForeignKeys)
+	}
+
+	users := tables[1]
+	if users.Columns[2].Default == nil || *users.Columns[2].Default != "'active'" {
+		t.Fatalf("unexpected default: %+v", users.Columns[2])
+	}
+	if len(users.Indexes) != 1 || !users.Indexes[0].Unique {
+		t.Fatalf("expected unique autoindex on users: %+v", users.Indexes)
+	}
+}
@@ -0,0 +1,34 @@
+// Package sqlitetest provides fixtures for tests of code built on
+// sqlite-base. It lives in its own package so that production code does not
+// import testing.
+package sqlitetest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	sqlitebase "github.com/kahnwong/sqlite-base"
+)
+
+// TestDB opens a database in a file under t.TempDir, creates the given
+// tables and validates them, and closes the database when the test ends.
+// Unlike :memory:, the file-backed database behaves like production with
+// several pooled connections. Any failure stops the test.
+func TestDB(t testing.TB, schemas ...sqlitebase.TableDefinition) *sqlx.DB {
+	t.Helper()
+
+	db, err := sqlitebase.Open(sqlitebase.Config{Path: filepath.Join(t.TempDir(), "test.sqlite")})
+	if err != nil {
+		t.Fatalf("open test database failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if len(schemas) > 0 {
+		if _, err := sqlitebase.InitSchema(db, nil, "", sqlitebase.Schema{Tables: schemas}); err != nil {
+			t.Fatalf("create test schema failed: %v", err)
+		}
+	}
+
+	return db
+}
@@ -0,0 +1,32 @@
+package sqlitetest
+
+import (
+	"testing"
+
+	sqlitebase "github.com/kahnwong/sqlite-base"
+)
+
+func TestTestDB(t *testing.T) {
+	t.Parallel()
+
+	db := TestDB(t, sqlitebase.TableDefinition{Name: "users", Columns: []sqlitebase.ColumnSpec{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "email", Type: "TEXT", NotNull: true, Unique: true},
+	}})
+
+	if _, err := db.Exec("INSERT INTO users (email) VALUES (?)", "a@example.com"); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	tables, err := sqlitebase.ListTables(db)
+	if err != nil {
+		t.Fatalf("list tables failed: %v", err)
+	}
+	if len(tables) != 1 || tables[0] != "users" {
+		t.Fatalf("unexpected tables: %v", tables)
+	}
+
+	other := TestDB(t)
+	if tables, _ := sqlitebase.ListTables(other); len(tables) != 0 {
+		t.Fatalf("expected an independent empty database, got %v", tables)
+	}
+}
@@ -0,0 +1,68 @@
+package sqlite_base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// StmtCache keeps one prepared statement per SQL string for repeated
+// queries. It is safe for concurrent use; database/sql re-prepares a cached
+// statement on whichever pooled connection executes it.
+type StmtCache struct {
+	db *sqlx.DB
+
+	mu     sync.Mutex
+	stmts  map[string]*sqlx.Stmt
+	closed bool
+}
+
+func NewStmtCache(db *sqlx.DB) *StmtCache {
+	return &StmtCache{db: db, stmts: make(map[string]*sqlx.Stmt)}
+}
+
+// Get returns the cached statement for query, preparing it on first use.
+func (c *StmtCache) Get(query string) (*sqlx.Stmt, error) {
+	return c.GetContext(context.Background(), query)
+}
+
+func (c *StmtCache) GetContext(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, errors.New("statement cache is closed")
+	}
+	if stmt, ok := c.stmts[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := c.db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("prepare statement: %w", err)
+	}
+	c.stmts[query] = stmt
+
+	return stmt, nil
+}
+
+// Close closes every cached statement. Statements obtained from the cache
+// must not be used afterwards.
+func (c *StmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for query, stmt := range c.stmts {
+		if err := stmt.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close statement %q: %w", query, err))
+		}
+	}
+	c.stmts = make(map[string]*sqlx.Stmt)
+	c.closed = true
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+package sqlite_base
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestStmtCache(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE hits (id INTEGER PRIMARY KEY, path TEXT NOT NULL)")
+	cache := NewStmtCache(db)
+
+	const insert = "INSERT INTO hits (path) VALUES (?)"
+	first, err := cache.Get(insert)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stmt, err := cache.Get(insert)
+			if err != nil {
+				t.Errorf("get failed: %v", err)
+				return
+			}
+			if stmt != first {
+				t.Errorf("expected cached statement to be reused")
+			}
+			if _, err := stmt.Exec("/"); err != nil {
+				t.Errorf("exec failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM hits"); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if count != 10 {
+		t.Fatalf("expected 10 rows, got %d", count)
+	}
+
+	if _, err := cache.Get("SELECT nope FROM"); err == nil {
+		t.Fatal("expected invalid SQL to fail")
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if _, err := cache.Get(insert); err == nil {
+		t.Fatal("expected closed cache to fail")
+	}
+}
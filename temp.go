@@ -0,0 +1,61 @@
+package sqlite_base
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TempTable is a temporary table created by CreateTempTable together with
+// the connection it lives on. Every statement that uses the table must run
+// on the embedded connection.
+type TempTable struct {
+	*sqlx.Conn
+	name string
+}
+
+// Close drops the temporary table and returns the connection to the pool.
+// If the table cannot be dropped the connection is discarded instead, so
+// that the table never leaks to a later user of the pool.
+func (t *TempTable) Close() error {
+	_, err := t.Conn.ExecContext(context.Background(), "DROP TABLE IF EXISTS temp."+quoteIdent(t.name))
+	if err != nil {
+		_ = t.Conn.Raw(func(any) error { return driver.ErrBadConn })
+		_ = t.Conn.Close()
+		return fmt.Errorf("drop temp table %s: %w", t.name, err)
+	}
+
+	return t.Conn.Close()
+}
+
+// CreateTempTable checks out a dedicated connection and runs CREATE TEMP
+// TABLE name (schema) on it, where schema is the column list, e.g.
+// "id INTEGER, total REAL". Temporary tables only exist on the connection
+// that created them, so the caller must run every statement that uses the
+// table on the returned TempTable and close it when done, which drops the
+// table before the connection goes back to the pool.
+func CreateTempTable(db *sqlx.DB, name, schema string) (*TempTable, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("table name is required")
+	}
+	if strings.TrimSpace(schema) == "" {
+		return nil, fmt.Errorf("temp table %s: schema is required", name)
+	}
+
+	ctx := context.Background()
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("checkout connection: %w", err)
+	}
+	stmt := fmt.Sprintf("CREATE TEMP TABLE %s (%s)", quoteIdent(name), schema)
+	if _, err := conn.ExecContext(ctx, stmt); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("create temp table %s: %w", name, err)
+	}
+
+	return &TempTable{Conn: conn, name: name}, nil
+}
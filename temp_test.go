@@ -0,0 +1,69 @@
+package sqlite_base
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateTempTable(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{Path: filepath.Join(t.TempDir(), "app.sqlite")})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(2)
+
+	ctx := context.Background()
+	conn, err := CreateTempTable(db, "scratch", "id INTEGER PRIMARY KEY, total REAL")
+	if err != nil {
+		t.Fatalf("create temp table failed: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, "INSERT INTO scratch (total) VALUES (1.5), (2.5)"); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	var total float64
+	if err := conn.GetContext(ctx, &total, "SELECT SUM(total) FROM scratch"); err != nil {
+		t.Fatalf("sum failed: %v", err)
+	}
+	if total != 4 {
+		t.Fatalf("expected 4, got %v", total)
+	}
+
+	other, err := db.Connx(ctx)
+	if err != nil {
+		t.Fatalf("checkout failed: %v", err)
+	}
+	if _, err := other.ExecContext(ctx, "SELECT * FROM scratch"); err == nil {
+		t.Fatal("expected temp table to be invisible to other connections")
+	}
+	_ = other.Close()
+	if err := conn.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	db.SetMaxOpenConns(1)
+	again, err := CreateTempTable(db, "scratch", "id INTEGER PRIMARY KEY")
+	if err != nil {
+		t.Fatalf("create temp table again failed: %v", err)
+	}
+	var rows int
+	if err := again.GetContext(ctx, &rows, "SELECT COUNT(*) FROM scratch"); err != nil || rows != 0 {
+		t.Fatalf("expected a fresh empty table, got %d rows, %v", rows, err)
+	}
+	if err := again.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if _, err := db.Exec("SELECT * FROM scratch"); err == nil {
+		t.Fatal("expected the temp table to be dropped on close")
+	}
+
+	if _, err := CreateTempTable(db, "broken", "id INTEGER,"); err == nil {
+		t.Fatal("expected invalid schema to fail")
+	}
+	if _, err := CreateTempTable(db, "empty", " "); err == nil {
+		t.Fatal("expected empty schema to fail")
+	}
+}
@@ -0,0 +1,109 @@
+package sqlite_base
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// WithTx runs fn inside a transaction. The transaction is committed when fn
+// returns nil and rolled back when it returns an error or panics; panics are
+// re-raised after the rollback.
+func WithTx(db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+	return WithTxContext(context.Background(), db, nil, fn)
+}
+
+func WithTxContext(ctx context.Context, db *sqlx.DB, opts *sql.TxOptions, fn func(*sqlx.Tx) error) (err error) {
+	tx, err := db.BeginTxx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+var savepointName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// WithSavepoint runs fn inside SAVEPOINT name on tx. The savepoint is
+// released when fn returns nil; when fn returns an error or panics, the work
+// done since the savepoint is rolled back and the savepoint released, leaving
+// the enclosing transaction usable. Panics are re-raised afterwards.
+func WithSavepoint(tx *sqlx.Tx, name string, fn func() error) (err error) {
+	if !savepointName.MatchString(name) {
+		return fmt.Errorf("invalid savepoint name %q", name)
+	}
+	if _, err := tx.Exec("SAVEPOINT " + name); err != nil {
+		return fmt.Errorf("create savepoint %s: %w", name, err)
+	}
+
+	rollback := func() error {
+		if _, err := tx.Exec("ROLLBACK TO " + name); err != nil {
+			return err
+		}
+		_, err := tx.Exec("RELEASE " + name)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(); err != nil {
+		if rbErr := rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint %s: %v)", err, name, rbErr)
+		}
+		return err
+	}
+
+	if _, err := tx.Exec("RELEASE " + name); err != nil {
+		return fmt.Errorf("release savepoint %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// RetryWrite runs fn in a transaction like WithTx and, when the transaction
+// fails with SQLITE_BUSY or SQLITE_LOCKED, runs it again up to maxAttempts
+// times in total, sleeping backoff before the second attempt and doubling
+// the delay after each one. Any other error is returned immediately, and
+// fn must be safe to run more than once.
+func RetryWrite(db *sqlx.DB, maxAttempts int, backoff time.Duration, fn func(*sqlx.Tx) error) error {
+	if maxAttempts < 1 {
+		return fmt.Errorf("max attempts must be at least 1, got %d", maxAttempts)
+	}
+
+	delay := backoff
+	for attempt := 1; ; attempt++ {
+		err := WithTx(db, fn)
+		if err == nil || attempt == maxAttempts || !(IsBusy(err) || IsLocked(err)) {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,156 @@
+package sqlite_base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestWithTx_CommitsAndRollsBack(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+
+	err := WithTx(db, func(tx *sqlx.Tx) error {
+		_, err := tx.Exec("INSERT INTO items (name) VALUES ('kept')")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("with tx failed: %v", err)
+	}
+
+	errBoom := errors.New("boom")
+	err = WithTx(db, func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec("INSERT INTO items (name) VALUES ('discarded')"); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected fn error, got: %v", err)
+	}
+
+	func() {
+		defer func() {
+			if p := recover(); p != "panic in tx" {
+				t.Fatalf("expected panic to be re-raised, got: %v", p)
+			}
+		}()
+		_ = WithTxContext(context.Background(), db, nil, func(tx *sqlx.Tx) error {
+			tx.MustExec("INSERT INTO items (name) VALUES ('panicked')")
+			panic("panic in tx")
+		})
+	}()
+
+	var names []string
+	if err := db.Select(&names, "SELECT name FROM items"); err != nil {
+		t.Fatalf("select failed: %v", err)
+	}
+	if len(names) != 1 || names[0] != "kept" {
+		t.Fatalf("expected only committed row, got %v", names)
+	}
+}
+
+func TestWithSavepoint_RollsBackInnerStepOnly(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+
+	innerErr := errors.New("inner failed")
+	err := WithTx(db, func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec("INSERT INTO items (name) VALUES ('outer')"); err != nil {
+			return err
+		}
+		err := WithSavepoint(tx, "inner_step", func() error {
+			if _, err := tx.Exec("INSERT INTO items (name) VALUES ('inner')"); err != nil {
+				return err
+			}
+			return innerErr
+		})
+		if !errors.Is(err, innerErr) {
+			t.Fatalf("expected inner error, got %v", err)
+		}
+		return WithSavepoint(tx, "kept", func() error {
+			_, err := tx.Exec("INSERT INTO items (name) VALUES ('kept')")
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+
+	var names []string
+	if err := db.Select(&names, "SELECT name FROM items ORDER BY id"); err != nil {
+		t.Fatalf("select failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "outer" || names[1] != "kept" {
+		t.Fatalf("unexpected rows: %v", names)
+	}
+
+	err = WithTx(db, func(tx *sqlx.Tx) error {
+		return WithSavepoint(tx, "bad name; DROP TABLE items", func() error { return nil })
+	})
+	if err == nil {
+		t.Fatal("expected invalid savepoint name to fail")
+	}
+}
+
+func TestRetryWrite(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t, "CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT)")
+
+	attempts := 0
+	err := RetryWrite(db, 5, time.Millisecond, func(tx *sqlx.Tx) error {
+		attempts++
+		if _, err := tx.Exec("INSERT INTO events (name) VALUES ('retried')"); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return fmt.Errorf("write events: %w", sqlite3.Error{Code: sqlite3.ErrBusy})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("retry write failed: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM events"); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected failed attempts to roll back, got %d rows", count)
+	}
+
+	attempts = 0
+	err = RetryWrite(db, 3, time.Millisecond, func(*sqlx.Tx) error {
+		attempts++
+		return sqlite3.Error{Code: sqlite3.ErrLocked}
+	})
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrLocked || attempts != 3 {
+		t.Fatalf("expected locked error after 3 attempts, got %v after %d", err, attempts)
+	}
+
+	attempts = 0
+	err = RetryWrite(db, 3, time.Millisecond, func(tx *sqlx.Tx) error {
+		attempts++
+		_, err := tx.Exec("INSERT INTO missing (id) VALUES (1)")
+		return err
+	})
+	if err == nil || attempts != 1 {
+		t.Fatalf("expected non-retryable error on first attempt, got %v after %d", err, attempts)
+	}
+
+	if err := RetryWrite(db, 0, 0, func(*sqlx.Tx) error { return nil }); err == nil {
+		t.Fatal("expected invalid max attempts to fail")
+	}
+}
@@ -0,0 +1,88 @@
+package sqlite_base
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SQLiteVersion returns the version of the SQLite library linked into the
+// driver, e.g. "3.45.1".
+func SQLiteVersion(db *sqlx.DB) (string, error) {
+	var version string
+	if err := db.Get(&version, "SELECT sqlite_version()"); err != nil {
+		return "", fmt.Errorf("query sqlite version: %w", err)
+	}
+
+	return version, nil
+}
+
+// SQLiteVersionParts returns SQLiteVersion as numbers for comparisons.
+func SQLiteVersionParts(db *sqlx.DB) (major, minor, patch int, err error) {
+	version, err := sqliteVersion(db)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	return version[0], version[1], version[2], nil
+}
+
+// linkedVersion caches sqliteVersion, which internal checks call on every
+// use. go-sqlite3 links a single SQLite library into the process, so every
+// database reports the same version.
+var linkedVersion struct {
+	sync.Mutex
+	parts [3]int
+	ok    bool
+}
+
+func sqliteVersion(db *sqlx.DB) ([3]int, error) {
+	linkedVersion.Lock()
+	defer linkedVersion.Unlock()
+	if linkedVersion.ok {
+		return linkedVersion.parts, nil
+	}
+
+	version, err := SQLiteVersion(db)
+	if err != nil {
+		return [3]int{}, err
+	}
+	parts, err := parseVersion(version)
+	if err != nil {
+		return [3]int{}, err
+	}
+	linkedVersion.parts, linkedVersion.ok = parts, true
+
+	return parts, nil
+}
+
+func parseVersion(version string) ([3]int, error) {
+	var parts [3]int
+	fields := strings.Split(version, ".")
+	if len(fields) < 2 || len(fields) > 3 {
+		return parts, fmt.Errorf("unexpected sqlite version %q", version)
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return parts, fmt.Errorf("unexpected sqlite version %q", version)
+		}
+		parts[i] = n
+	}
+
+	return parts, nil
+}
+
+func versionAtLeast(version [3]int, major, minor, patch int) bool {
+	want := [3]int{major, minor, patch}
+	for i := range version {
+		if version[i] != want[i] {
+			return version[i] > want[i]
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,55 @@
+package sqlite_base
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSQLiteVersion(t *testing.T) {
+	t.Parallel()
+
+	db := newTestDB(t)
+
+	version, err := SQLiteVersion(db)
+	if err != nil {
+		t.Fatalf("sqlite version failed: %v", err)
+	}
+	major, minor, patch, err := SQLiteVersionParts(db)
+	if err != nil {
+		t.Fatalf("sqlite version parts failed: %v", err)
+	}
+	if got := fmt.Sprintf("%d.%d.%d", major, minor, patch); got != version {
+		t.Fatalf("expected parts to match %s, got %s", version, got)
+	}
+	if major != 3 {
+		t.Fatalf("expected SQLite 3, got %s", version)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    [3]int
+		wantErr bool
+	}{
+		{"3.45.1", [3]int{3, 45, 1}, false},
+		{"3.8", [3]int{3, 8, 0}, false},
+		{"3", [3]int{}, true},
+		{"3.x.1", [3]int{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseVersion(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseVersion(%q) error = %v", tt.in, err)
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Fatalf("parseVersion(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	if !versionAtLeast([3]int{3, 25, 0}, 3, 25, 0) || versionAtLeast([3]int{3, 24, 9}, 3, 25, 0) {
+		t.Fatal("versionAtLeast compared versions incorrectly")
+	}
+}
@@ -0,0 +1,187 @@
+package sqlite_base
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type CheckpointMode string
+
+const (
+	CheckpointPassive  CheckpointMode = "PASSIVE"
+	CheckpointFull     CheckpointMode = "FULL"
+	CheckpointRestart  CheckpointMode = "RESTART"
+	CheckpointTruncate CheckpointMode = "TRUNCATE"
+)
+
+// Checkpoint runs PRAGMA wal_checkpoint(mode) and returns SQLite's three
+// results: whether the checkpoint was blocked by another connection, the
+// number of frames in the WAL and the number of frames copied back into the
+// database. Outside WAL mode all three are -1 or 0.
+func Checkpoint(db *sqlx.DB, mode CheckpointMode) (busy, logFrames, checkpointedFrames int, err error) {
+	switch mode {
+	case CheckpointPassive, CheckpointFull, CheckpointRestart, CheckpointTruncate:
+	default:
+		return 0, 0, 0, fmt.Errorf("unknown checkpoint mode %q", mode)
+	}
+
+	err = db.QueryRow(fmt.Sprintf("PRAGMA wal_checkpoint(%s)", mode)).Scan(&busy, &logFrames, &checkpointedFrames)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("checkpoint wal: %w", err)
+	}
+
+	return busy, logFrames, checkpointedFrames, nil
+}
+
+// SetWALAutocheckpoint sets PRAGMA wal_autocheckpoint, the WAL size in pages
+// that triggers an automatic checkpoint; 0 or a negative value disables it.
+// The setting is per connection and this call only reaches one pooled
+// connection, so use Config.Pragmas to apply it to every connection.
+func SetWALAutocheckpoint(db *sqlx.DB, frames int) error {
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA wal_autocheckpoint = %d", frames)); err != nil {
+		return fmt.Errorf("set wal autocheckpoint: %w", err)
+	}
+
+	return nil
+}
+
+// The wal-index in the -shm file starts with two copies of a 48-byte header,
+// whose mxFrame field is the number of valid frames in the WAL, followed by
+// the checkpoint information, whose nBackfill field is the number of those
+// frames already copied into the database. These are the log and
+// checkpointed counts PRAGMA wal_checkpoint reports. Fields are stored in
+// the host's byte order. See https://www.sqlite.org/walformat.html.
+const (
+	walIndexHeaderSize   = 48
+	walIndexMaxFrame     = 16
+	walIndexBackfill     = 2 * walIndexHeaderSize
+	walIndexReadSize     = walIndexBackfill + 4
+	walIndexReadAttempts = 3
+)
+
+// MonitorWAL checks the WAL in a new goroutine every interval and calls
+// onThreshold with the number of frames not yet checkpointed into the
+// database whenever it exceeds threshold, e.g. to force a checkpoint or
+// raise an alert when readers keep automatic checkpoints from catching up.
+// The count is the log minus checkpointed frames Checkpoint would report,
+// read from the wal-index in the -shm file so that the check has no side
+// effects on the database; it drops back to zero after any checkpoint that
+// copies every frame, not only a TRUNCATE. A missing -shm file counts as
+// zero frames, so outside WAL mode the callback never runs; with
+// locking_mode EXCLUSIVE the wal-index lives in memory and the WAL cannot be
+// observed. Failed checks are passed to onError, which may be nil. The
+// returned stop ends the goroutine and waits for it; it is safe to call more
+// than once.
+func MonitorWAL(db *sqlx.DB, interval time.Duration, threshold int, onThreshold func(frames int), onError func(error)) (stop func(), err error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("monitor wal: interval must be positive, got %s", interval)
+	}
+	if onThreshold == nil {
+		return nil, errors.New("monitor wal: onThreshold is nil")
+	}
+
+	path, err := databaseFile(db)
+	if err != nil {
+		return nil, fmt.Errorf("monitor wal: %w", err)
+	}
+	shmPath := path + "-shm"
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				frames, err := pendingWALFrames(shmPath)
+				if err != nil {
+					if onError != nil {
+						onError(fmt.Errorf("monitor wal: %w", err))
+					}
+					continue
+				}
+				if frames > threshold {
+					onThreshold(frames)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-finished
+		})
+	}, nil
+}
+
+// databaseFile returns the path of the main database file. In-memory and
+// temporary databases have none.
+func databaseFile(db *sqlx.DB) (string, error) {
+	var databases []struct {
+		Seq  int    `db:"seq"`
+		Name string `db:"name"`
+		File string `db:"file"`
+	}
+	if err := db.Select(&databases, "PRAGMA database_list"); err != nil {
+		return "", fmt.Errorf("list databases: %w", err)
+	}
+	for _, database := range databases {
+		if database.Name == "main" && database.File != "" {
+			return database.File, nil
+		}
+	}
+
+	return "", errors.New("database has no file")
+}
+
+// pendingWALFrames reads the wal-index header from the -shm file at path and
+// returns the number of frames not yet checkpointed. A writer may be
+// updating the header, in which case its two copies differ and the read is
+// retried.
+func pendingWALFrames(path string) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("open wal index: %w", err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, walIndexReadSize)
+	for attempt := 0; attempt < walIndexReadAttempts; attempt++ {
+		n, err := f.ReadAt(buf, 0)
+		if n < len(buf) {
+			if errors.Is(err, io.EOF) {
+				return 0, nil
+			}
+			return 0, fmt.Errorf("read wal index: %w", err)
+		}
+		if !bytes.Equal(buf[:walIndexHeaderSize], buf[walIndexHeaderSize:2*walIndexHeaderSize]) {
+			continue
+		}
+		maxFrame := binary.NativeEndian.Uint32(buf[walIndexMaxFrame:])
+		backfill := binary.NativeEndian.Uint32(buf[walIndexBackfill:])
+		if backfill > maxFrame {
+			return 0, nil
+		}
+		return int(maxFrame - backfill), nil
+	}
+
+	return 0, errors.New("read wal index: header is being updated")
+}
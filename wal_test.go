@@ -0,0 +1,196 @@
+package sqlite_base
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{
+		DSN:     DSN{Path: filepath.Join(t.TempDir(), "app.sqlite"), JournalMode: "WAL"},
+		Pragmas: map[string]string{"wal_autocheckpoint": "0"},
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+
+	db.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY, body TEXT)")
+	for i := 0; i < 20; i++ {
+		db.MustExec("INSERT INTO items (body) VALUES ('x')")
+	}
+
+	busy, logFrames, checkpointed, err := Checkpoint(db, CheckpointPassive)
+	if err != nil {
+		t.Fatalf("passive checkpoint failed: %v", err)
+	}
+	if busy != 0 || logFrames == 0 || checkpointed != logFrames {
+		t.Fatalf("unexpected passive checkpoint result: busy=%d log=%d checkpointed=%d", busy, logFrames, checkpointed)
+	}
+
+	_, logFrames, _, err = Checkpoint(db, CheckpointTruncate)
+	if err != nil {
+		t.Fatalf("truncate checkpoint failed: %v", err)
+	}
+	if logFrames != 0 {
+		t.Fatalf("expected truncated wal, got %d frames", logFrames)
+	}
+
+	if _, _, _, err := Checkpoint(db, "NOW"); err == nil {
+		t.Fatal("expected unknown mode to fail")
+	}
+
+	if err := SetWALAutocheckpoint(db, 500); err != nil {
+		t.Fatalf("set autocheckpoint failed: %v", err)
+	}
+	var frames int
+	if err := db.Get(&frames, "PRAGMA wal_autocheckpoint"); err != nil {
+		t.Fatalf("read autocheckpoint failed: %v", err)
+	}
+	if frames != 500 {
+		t.Fatalf("expected autocheckpoint 500, got %d", frames)
+	}
+}
+
+func TestMonitorWAL(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "app.sqlite")
+	db, err := Open(Config{
+		DSN:     DSN{Path: path, JournalMode: "WAL"},
+		Pragmas: map[string]string{"wal_autocheckpoint": "0"},
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	db.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY, body TEXT)")
+	for i := 0; i < 20; i++ {
+		db.MustExec("INSERT INTO items (body) VALUES ('x')")
+	}
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat database failed: %v", err)
+	}
+
+	reports := make(chan int, 100)
+	stop, err := MonitorWAL(db, 5*time.Millisecond, 10, func(frames int) { reports <- frames }, func(err error) { t.Errorf("monitor failed: %v", err) })
+	if err != nil {
+		t.Fatalf("monitor wal failed: %v", err)
+	}
+	select {
+	case frames := <-reports:
+		if frames <= 10 {
+			t.Fatalf("expected more than 10 frames, got %d", frames)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the threshold callback to run")
+	}
+	stop()
+	stop()
+
+	for len(reports) > 0 {
+		<-reports
+	}
+	time.Sleep(20 * time.Millisecond)
+	if len(reports) != 0 {
+		t.Fatal("expected no callbacks after stop")
+	}
+
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat database failed: %v", err)
+	}
+	if after.Size() != before.Size() {
+		t.Fatalf("expected monitoring not to checkpoint, database grew from %d to %d bytes", before.Size(), after.Size())
+	}
+
+	quiet := make(chan int, 1)
+	stop, err = MonitorWAL(db, 5*time.Millisecond, 1_000_000, func(frames int) { quiet <- frames }, nil)
+	if err != nil {
+		t.Fatalf("monitor wal failed: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	stop()
+	if len(quiet) != 0 {
+		t.Fatal("expected no callback below the threshold")
+	}
+}
+
+func TestMonitorWAL_ResetsAfterPassiveCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{
+		DSN:     DSN{Path: filepath.Join(t.TempDir(), "app.sqlite"), JournalMode: "WAL"},
+		Pragmas: map[string]string{"wal_autocheckpoint": "0"},
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	db.MustExec("CREATE TABLE items (id INTEGER PRIMARY KEY, body TEXT)")
+	for i := 0; i < 20; i++ {
+		db.MustExec("INSERT INTO items (body) VALUES ('x')")
+	}
+
+	reports := make(chan int, 1000)
+	stop, err := MonitorWAL(db, 5*time.Millisecond, 10, func(frames int) { reports <- frames }, func(err error) { t.Errorf("monitor failed: %v", err) })
+	if err != nil {
+		t.Fatalf("monitor wal failed: %v", err)
+	}
+	t.Cleanup(stop)
+	var reported int
+	select {
+	case reported = <-reports:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the threshold callback to run")
+	}
+
+	_, logFrames, checkpointed, err := Checkpoint(db, CheckpointPassive)
+	if err != nil {
+		t.Fatalf("passive checkpoint failed: %v", err)
+	}
+	if reported != logFrames {
+		t.Fatalf("expected the monitor to report the %d frames wal_checkpoint sees, got %d", logFrames, reported)
+	}
+	if checkpointed != logFrames {
+		t.Fatalf("expected the passive checkpoint to copy every frame, got %d of %d", checkpointed, logFrames)
+	}
+
+	// Let any tick that raced the checkpoint finish before draining.
+	time.Sleep(20 * time.Millisecond)
+	for len(reports) > 0 {
+		<-reports
+	}
+	time.Sleep(30 * time.Millisecond)
+	if len(reports) != 0 {
+		t.Fatalf("expected no callbacks after a passive checkpoint, got %d", len(reports))
+	}
+}
+
+func TestMonitorWAL_RejectsInvalidArguments(t *testing.T) {
+	t.Parallel()
+
+	db, err := Open(Config{DSN: DSN{Path: filepath.Join(t.TempDir(), "app.sqlite"), JournalMode: "WAL"}})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if _, err := MonitorWAL(db, 0, 10, func(int) {}, nil); err == nil {
+		t.Fatal("expected a zero interval to fail")
+	}
+	if _, err := MonitorWAL(db, time.Second, 10, nil, nil); err == nil {
+		t.Fatal("expected a nil callback to fail")
+	}
+	if _, err := MonitorWAL(newTestDB(t), time.Second, 10, func(int) {}, nil); err == nil {
+		t.Fatal("expected an in-memory database to fail")
+	}
+}